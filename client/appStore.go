@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"errors"
 
 	"github.com/mitchellh/mapstructure"
@@ -45,6 +46,7 @@ const GRAPHQL_URL = "/graphql"
 type AppStoreClient struct {
 	graphqlUrl string
 	client     graphqlClient
+	headers    map[string]string
 }
 
 type App struct {
@@ -56,7 +58,11 @@ type App struct {
 }
 
 func (self *AppStoreClient) Gql(query string, variables map[string]interface{}) (*map[string]interface{}, error) {
-	return self.client.Gql(self.graphqlUrl, query, variables)
+	return self.GqlWithContext(context.Background(), query, variables)
+}
+
+func (self *AppStoreClient) GqlWithContext(ctx context.Context, query string, variables map[string]interface{}) (*map[string]interface{}, error) {
+	return self.client.GqlWithContext(contextWithDefaultHeaders(ctx, self.headers), self.graphqlUrl, query, variables)
 }
 
 func (self *AppStoreClient) GetAppStoreListing(id string) (*App, error) {
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TestingT is the part of *testing.T used by the DryRunRecorder assertions.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertInvoked fails the test unless a function with the given name was
+// invoked. The name matches with or without its alias or version qualifier.
+func (r *DryRunRecorder) AssertInvoked(t TestingT, functionName string) bool {
+	t.Helper()
+	for _, invocation := range r.Invocations() {
+		if invocation.invokes(functionName) {
+			return true
+		}
+	}
+	t.Errorf("Expected %s to be invoked, got %s", functionName, r.describe())
+	return false
+}
+
+// AssertInvokedWithPath fails the test unless a function was invoked with
+// the given request path.
+func (r *DryRunRecorder) AssertInvokedWithPath(t TestingT, path string) bool {
+	t.Helper()
+	for _, invocation := range r.Invocations() {
+		if invocation.Payload.Path == path {
+			return true
+		}
+	}
+	t.Errorf("Expected an invocation with path %s, got %s", path, r.describe())
+	return false
+}
+
+// AssertNotInvoked fails the test if a function with the given name was
+// invoked. The name matches with or without its alias or version qualifier.
+func (r *DryRunRecorder) AssertNotInvoked(t TestingT, functionName string) bool {
+	t.Helper()
+	for _, invocation := range r.Invocations() {
+		if invocation.invokes(functionName) {
+			t.Errorf("Expected %s not to be invoked, got %s", functionName, r.describe())
+			return false
+		}
+	}
+	return true
+}
+
+func (p PlannedInvocation) invokes(functionName string) bool {
+	if p.FunctionName == functionName {
+		return true
+	}
+	name, _, _ := strings.Cut(p.FunctionName, ":")
+	return name == functionName
+}
+
+func (r *DryRunRecorder) describe() string {
+	invocations := r.Invocations()
+	if len(invocations) == 0 {
+		return "no invocations"
+	}
+	descriptions := make([]string, len(invocations))
+	for i, invocation := range invocations {
+		descriptions[i] = fmt.Sprintf("%s %s %s", invocation.FunctionName, invocation.Payload.HttpMethod, invocation.Payload.Path)
+	}
+	return fmt.Sprintf("%d invocations: %s", len(invocations), strings.Join(descriptions, ", "))
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type recordingT struct {
+	failures []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestDryRunRecorderAssertions(t *testing.T) {
+	recorder := NewDryRunRecorder(nil)
+	client := (&LambdaClient{}).Clone(WithDryRunRecorder(recorder))
+	_, _ = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, nil)
+
+	passing := recordingT{}
+	if !recorder.AssertInvoked(&passing, "some_lambda") || !recorder.AssertInvoked(&passing, "some_lambda:status") {
+		t.Fatal("Expected the function to be invoked", passing.failures)
+	}
+	if !recorder.AssertInvokedWithPath(&passing, "/some/path") {
+		t.Fatal("Expected the path to be invoked", passing.failures)
+	}
+	if !recorder.AssertNotInvoked(&passing, "other_lambda") {
+		t.Fatal("Expected the other function not to be invoked", passing.failures)
+	}
+
+	failing := recordingT{}
+	recorder.AssertInvoked(&failing, "other_lambda")
+	recorder.AssertInvokedWithPath(&failing, "/other/path")
+	recorder.AssertNotInvoked(&failing, "some_lambda")
+	if len(failing.failures) != 3 {
+		t.Fatal("Expected every assertion to fail", failing.failures)
+	}
+	if !strings.Contains(failing.failures[0], "some_lambda:status POST /some/path") {
+		t.Fatal("Expected the failure to list the recorded invocations", failing.failures[0])
+	}
+}
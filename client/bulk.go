@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// GqlResult is the outcome of one GraphQL request made as part of a larger
+// operation. Err is set when the request failed. Data holds the data of the
+// response, which is nil when the server returned no data, even without an
+// error. A response holding partial data along with GraphQL errors sets both
+// Data and Err.
+type GqlResult struct {
+	Data map[string]interface{}
+	Err  error
+}
+
+// InvokeRequest is one request of an InvokeAll fan-out: either a GraphQL
+// query sent to URI, or, when HTTPRequest is set, a request sent with Do.
+type InvokeRequest struct {
+	URI       string
+	Query     string
+	Variables map[string]interface{}
+	// HTTPRequest is sent with Do instead of a GraphQL query. Its context is
+	// replaced by the one given to InvokeAll.
+	HTTPRequest *http.Request
+}
+
+// InvokeResult is the outcome of one request of an InvokeAll fan-out. Data
+// is set for GraphQL requests and Response for requests sent with Do; Err is
+// set when the request failed. Like GqlResult, a GraphQL response holding
+// partial data along with errors sets both Data and Err.
+type InvokeResult struct {
+	Data     map[string]interface{}
+	Response *http.Response
+	Err      error
+}
+
+// InvokeAllOption configures InvokeAll.
+type InvokeAllOption func(*invokeAllSettings)
+
+type invokeAllSettings struct {
+	concurrency int
+}
+
+// InvokeAllConcurrency limits InvokeAll to n requests in flight. By default
+// every request is started at once, subject to WithMaxConcurrency.
+func InvokeAllConcurrency(n int) InvokeAllOption {
+	return func(s *invokeAllSettings) {
+		s.concurrency = n
+	}
+}
+
+// BulkMutate runs mutation once for each entry of variablesList, with at most
+// concurrency requests in flight. Results are returned in the same order as
+// variablesList, each holding its own data, error or both. Once ctx is done
+// no new requests are started; their results hold the context error, which
+// is also returned.
+func (c *LambdaClient) BulkMutate(ctx context.Context, uri string, mutation string, variablesList []map[string]interface{}, concurrency int) ([]GqlResult, error) {
+	results := make([]GqlResult, len(variablesList))
+	fanOut(ctx, len(variablesList), concurrency, func(i int) {
+		data, err := c.GqlWithContext(ctx, uri, mutation, variablesList[i])
+		if data != nil {
+			results[i].Data = *data
+		}
+		results[i].Err = err
+	}, func(i int, err error) {
+		results[i].Err = err
+	})
+	return results, ctx.Err()
+}
+
+// InvokeAll sends requests concurrently, so data can be assembled from
+// several services at once. Unlike BulkMutate each request can target a
+// different function, and can be a GraphQL query or a request sent with Do.
+// Results are returned in the same order as requests; once ctx is done no
+// new requests are started and their results hold the context error.
+func (c *LambdaClient) InvokeAll(ctx context.Context, requests []InvokeRequest, opts ...InvokeAllOption) []InvokeResult {
+	settings := invokeAllSettings{concurrency: len(requests)}
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	results := make([]InvokeResult, len(requests))
+	fanOut(ctx, len(requests), settings.concurrency, func(i int) {
+		request := requests[i]
+		if request.HTTPRequest != nil {
+			results[i].Response, results[i].Err = c.Do(request.HTTPRequest.WithContext(ctx))
+			return
+		}
+		data, err := c.GqlWithContext(ctx, request.URI, request.Query, request.Variables)
+		if data != nil {
+			results[i].Data = *data
+		}
+		results[i].Err = err
+	}, func(i int, err error) {
+		results[i].Err = err
+	})
+	return results
+}
+
+// fanOut calls run for each index below n, with at most concurrency calls
+// running at once. Once ctx is done, cancelled is called with the context
+// error for each index that was not started.
+func fanOut(ctx context.Context, n int, concurrency int, run func(i int), cancelled func(i int, err error)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			for j := i; j < n; j++ {
+				cancelled(j, ctx.Err())
+			}
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+}
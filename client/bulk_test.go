@@ -0,0 +1,212 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+// echoInvoker answers every request with its own variables as data, failing
+// requests whose variables contain "fail" and adding an error to those whose
+// variables contain "partial".
+type echoInvoker struct {
+	mu       sync.Mutex
+	active   int32
+	maxSeen  int32
+	delay    time.Duration
+	requests int32
+}
+
+func (e *echoInvoker) Invoke(ctx context.Context, input *lambda.InvokeInput, rest ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	atomic.AddInt32(&e.requests, 1)
+	active := atomic.AddInt32(&e.active, 1)
+	defer atomic.AddInt32(&e.active, -1)
+	e.mu.Lock()
+	if active > e.maxSeen {
+		e.maxSeen = active
+	}
+	e.mu.Unlock()
+	time.Sleep(e.delay)
+
+	var sent Payload
+	json.Unmarshal(input.Payload, &sent)
+	var body struct {
+		Variables map[string]interface{}
+	}
+	json.Unmarshal([]byte(sent.Body), &body)
+
+	response := map[string]interface{}{"data": body.Variables}
+	if _, ok := body.Variables["fail"]; ok {
+		response = map[string]interface{}{"errors": []interface{}{map[string]interface{}{"message": "failed"}}}
+	}
+	if _, ok := body.Variables["partial"]; ok {
+		response["errors"] = []interface{}{map[string]interface{}{"message": "partially failed"}}
+	}
+	responseBody, _ := json.Marshal(response)
+	out, _ := json.Marshal(responsePayload{Body: string(responseBody), StatusCode: 200})
+	return &lambda.InvokeOutput{Payload: out}, nil
+}
+
+func TestBulkMutate(t *testing.T) {
+	invoker := echoInvoker{delay: 5 * time.Millisecond}
+	client := LambdaClient{
+		invoker: &invoker,
+	}
+
+	variablesList := []map[string]interface{}{}
+	for i := 0; i < 10; i++ {
+		variablesList = append(variablesList, map[string]interface{}{"id": fmt.Sprint(i)})
+	}
+	variablesList[3]["fail"] = true
+	variablesList[5]["partial"] = true
+
+	results, err := client.BulkMutate(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, variablesList, 3)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	for i, result := range results {
+		if i == 3 {
+			if result.Err == nil || result.Err.Error() != "failed" {
+				t.Fatal("Expected item error", result)
+			}
+			continue
+		}
+		if i == 5 {
+			if result.Err == nil || result.Data["id"] != "5" {
+				t.Fatal("Expected partial data along with the error", result)
+			}
+			continue
+		}
+		if result.Err != nil || result.Data["id"] != fmt.Sprint(i) {
+			t.Fatal("Results are not in input order", i, result)
+		}
+	}
+	if invoker.maxSeen > 3 {
+		t.Fatal("Exceeded concurrency", invoker.maxSeen)
+	}
+}
+
+func TestBulkMutateCancelled(t *testing.T) {
+	invoker := echoInvoker{}
+	client := LambdaClient{
+		invoker: &invoker,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := client.BulkMutate(ctx, "some_lambda:status/some/path", MOCK_MUTATION, []map[string]interface{}{{}, {}}, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("Expected context error", err)
+	}
+	for _, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Fatal("Expected item context error", result)
+		}
+	}
+	if invoker.requests != 0 {
+		t.Fatal("Should not invoke after cancellation", invoker.requests)
+	}
+}
+
+func TestInvokeAll(t *testing.T) {
+	invoker := echoInvoker{delay: 5 * time.Millisecond}
+	client := LambdaClient{
+		invoker: &invoker,
+	}
+
+	requests := []InvokeRequest{
+		{URI: "patient-service:deployed/graphql", Query: MOCK_MUTATION, Variables: map[string]interface{}{"id": "0"}},
+		{URI: "fhir-service:deployed/graphql", Query: MOCK_MUTATION, Variables: map[string]interface{}{"fail": true}},
+		{URI: "data-service:deployed/graphql", Query: MOCK_MUTATION, Variables: map[string]interface{}{"id": "2"}},
+		{URI: "invalid", Query: MOCK_MUTATION},
+		{URI: "patient-service:deployed/graphql", Query: MOCK_MUTATION, Variables: map[string]interface{}{"id": "4", "partial": true}},
+	}
+	results := client.InvokeAll(context.Background(), requests, InvokeAllConcurrency(2))
+	if results[0].Err != nil || results[0].Data["id"] != "0" || results[2].Err != nil || results[2].Data["id"] != "2" {
+		t.Fatal("Results are not in input order", results)
+	}
+	if results[1].Err == nil || results[1].Err.Error() != "failed" {
+		t.Fatal("Expected request error", results[1])
+	}
+	if results[3].Err == nil {
+		t.Fatal("Expected an error for an invalid uri", results[3])
+	}
+	if results[4].Err == nil || results[4].Err.Error() != "partially failed" || results[4].Data["id"] != "4" {
+		t.Fatal("Expected partial data along with the error", results[4])
+	}
+	if invoker.maxSeen > 2 {
+		t.Fatal("Exceeded concurrency", invoker.maxSeen)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	invoker.requests = 0
+	for _, result := range client.InvokeAll(ctx, requests) {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Fatal("Expected context error", result)
+		}
+	}
+	if invoker.requests != 0 {
+		t.Fatal("Should not invoke after cancellation", invoker.requests)
+	}
+}
+
+func TestInvokeAllHTTPRequests(t *testing.T) {
+	invoker := echoInvoker{}
+	client := LambdaClient{
+		invoker: &invoker,
+	}
+
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+	results := client.InvokeAll(context.Background(), []InvokeRequest{
+		{HTTPRequest: req},
+		{URI: "data-service:deployed/graphql", Query: MOCK_MUTATION, Variables: map[string]interface{}{"id": "1"}},
+	})
+	if results[0].Err != nil || results[0].Response == nil || results[0].Response.StatusCode != 200 || results[0].Data != nil {
+		t.Fatal("Expected the Do response", results[0])
+	}
+	if results[1].Err != nil || results[1].Data["id"] != "1" || results[1].Response != nil {
+		t.Fatal("Expected the GraphQL data", results[1])
+	}
+	if invoker.requests != 2 {
+		t.Fatal("Expected both requests to be sent", invoker.requests)
+	}
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	invoker := echoInvoker{delay: 10 * time.Millisecond}
+	client := LambdaClient{
+		invoker: &invoker,
+	}
+	WithMaxConcurrency(2)(&client)
+
+	results, err := client.BulkMutate(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, make([]map[string]interface{}, 8), 8)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatal("Unexpected item error", result.Err)
+		}
+	}
+	if invoker.maxSeen != 2 {
+		t.Fatal("Expected at most 2 concurrent invocations", invoker.maxSeen)
+	}
+
+	client.invokeSlots.acquire(context.Background(), nil)
+	client.invokeSlots.acquire(context.Background(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("Expected to give up waiting for a slot", err)
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// CheckFunction verifies that the function of uri exists and that the
+// client's AWS credentials may invoke it, without running the function. It
+// makes a DryRun invocation, which needs the same lambda:InvokeFunction
+// permission as a real request and no other. It returns nil when the
+// function can be invoked, an error matching ErrFunctionNotFound when it
+// does not exist, or one matching ErrAccessDenied when the credentials may
+// not invoke it. Lambda reports a function the credentials may not see as
+// denied rather than missing. The path of uri is ignored, so a bare function
+// name, with or without a qualifier, works too. The check counts like any
+// other request against WithMaxConcurrency and the circuit breaker, and
+// passes through the invoke input mutator.
+func (c *LambdaClient) CheckFunction(ctx context.Context, uri string) error {
+	if atomic.LoadUint32(&c.closed) == 1 {
+		return ErrClientClosed
+	}
+	if !strings.Contains(uri, "/") {
+		uri += "/"
+	}
+	functionName, _, err := c.resolveUri(uri)
+	if err != nil {
+		return err
+	}
+	if c.dryRun != nil {
+		return nil
+	}
+	_, err = c.invokeAs(ctx, *functionName, types.InvocationTypeDryRun, nil)
+	if err == nil {
+		return nil
+	}
+	var notFound *types.ResourceNotFoundException
+	status, _ := InvokeStatusCode(err)
+	switch {
+	case errors.As(err, &notFound) || status == http.StatusNotFound:
+		return fmt.Errorf("%w: %s: %v", ErrFunctionNotFound, *functionName, err)
+	case status == http.StatusForbidden:
+		return fmt.Errorf("%w: %s: %v", ErrAccessDenied, *functionName, err)
+	}
+	return err
+}
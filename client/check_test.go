@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func invokeStatusFailure(status int) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      errors.New("failed"),
+		},
+	}
+}
+
+func TestCheckFunction(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{StatusCode: 204},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	err := client.CheckFunction(context.Background(), "some_lambda:status/some/path")
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if *mock.payload.FunctionName != "some_lambda:status" || mock.payload.InvocationType != types.InvocationTypeDryRun {
+		t.Fatal("Expected a DryRun invocation of the function", mock.payload)
+	}
+	err = client.CheckFunction(context.Background(), "some_lambda")
+	if err != nil || *mock.payload.FunctionName != "some_lambda" {
+		t.Fatal("Expected a bare function name to be accepted", mock.payload, err)
+	}
+
+	mock.err = &types.ResourceNotFoundException{}
+	err = client.CheckFunction(context.Background(), "some_lambda")
+	if !errors.Is(err, ErrFunctionNotFound) {
+		t.Fatal("Expected a not found error", err)
+	}
+
+	mock.err = invokeStatusFailure(http.StatusNotFound)
+	err = client.CheckFunction(context.Background(), "some_lambda")
+	if !errors.Is(err, ErrFunctionNotFound) {
+		t.Fatal("Expected a not found error for a 404", err)
+	}
+
+	mock.err = invokeStatusFailure(http.StatusForbidden)
+	err = client.CheckFunction(context.Background(), "some_lambda")
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected an access denied error", err)
+	}
+
+	mock.err = invokeStatusFailure(http.StatusInternalServerError)
+	err = client.CheckFunction(context.Background(), "some_lambda")
+	if err == nil || errors.Is(err, ErrFunctionNotFound) || errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected other failures to be returned as is", err)
+	}
+
+	mock.err = nil
+	var mutated bool
+	client.invokeInputMutator = func(input *lambda.InvokeInput) {
+		mutated = true
+	}
+	WithCircuitBreaker(CircuitBreakerSettings{FailureThreshold: 1, OpenTimeout: time.Minute})(&client)
+	client.circuitBreaker.record("some_lambda", true, client.now())
+	mock.hasBeenCalled = false
+	err = client.CheckFunction(context.Background(), "some_lambda")
+	if !errors.Is(err, ErrCircuitOpen) || mock.hasBeenCalled || !mutated {
+		t.Fatal("Expected the check to go through the mutator and circuit breaker", err, mutated)
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreakerSettings configures WithCircuitBreaker.
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive failed invocations of a
+	// function that opens its circuit.
+	FailureThreshold int
+	// OpenTimeout is how long a circuit stays open before a single probe
+	// request is let through to check whether the function has recovered.
+	OpenTimeout time.Duration
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+type circuit struct {
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+// circuitBreaker tracks a circuit per function name.
+type circuitBreaker struct {
+	settings CircuitBreakerSettings
+
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+}
+
+func newCircuitBreaker(settings CircuitBreakerSettings) *circuitBreaker {
+	if settings.FailureThreshold < 1 {
+		settings.FailureThreshold = 1
+	}
+	return &circuitBreaker{
+		settings: settings,
+		circuits: map[string]*circuit{},
+	}
+}
+
+// allow reports whether a request to functionName may be made. Once the open
+// timeout has passed only one probe is allowed until its outcome is recorded.
+func (b *circuitBreaker) allow(functionName string, now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	circuit, ok := b.circuits[functionName]
+	if !ok {
+		return true
+	}
+	switch circuit.state {
+	case circuitOpen:
+		if now.Sub(circuit.openedAt) < b.settings.OpenTimeout {
+			return false
+		}
+		circuit.state = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		return false
+	}
+	return true
+}
+
+// record updates the circuit of functionName with the outcome of a request.
+func (b *circuitBreaker) record(functionName string, failed bool, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !failed {
+		delete(b.circuits, functionName)
+		return
+	}
+	c, ok := b.circuits[functionName]
+	if !ok {
+		c = &circuit{}
+		b.circuits[functionName] = c
+	}
+	c.failures++
+	if c.state == circuitHalfOpen || c.failures >= b.settings.FailureThreshold {
+		c.state = circuitOpen
+		c.openedAt = now
+	}
+}
+
+// abandon is called instead of record when a request was given up by its
+// context, so a probe that never completed does not leave the circuit
+// half-open forever. The next request probes again.
+func (b *circuitBreaker) abandon(functionName string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if c, ok := b.circuits[functionName]; ok && c.state == circuitHalfOpen {
+		c.state = circuitOpen
+	}
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	mock := MockInvoker{
+		err: errors.New("invoke failed"),
+	}
+	now := time.Now()
+	client := LambdaClient{
+		invoker:        &mock,
+		circuitBreaker: newCircuitBreaker(CircuitBreakerSettings{FailureThreshold: 2, OpenTimeout: time.Minute}),
+		clock:          func() time.Time { return now },
+	}
+	invoke := func(functionName string) error {
+		mock.hasBeenCalled = false
+		_, err := client.invoke(context.Background(), functionName, []byte("{}"))
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := invoke("failing"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("Circuit should be closed below the threshold", i)
+		}
+	}
+	err := invoke("failing")
+	if !errors.Is(err, ErrCircuitOpen) || mock.hasBeenCalled {
+		t.Fatal("Circuit should be open after the threshold", err)
+	}
+
+	mock.err = nil
+	mock.response = &lambda.InvokeOutput{StatusCode: 200}
+	err = invoke("other")
+	if err != nil {
+		t.Fatal("Other functions should not be affected", err)
+	}
+
+	now = now.Add(time.Minute)
+	mock.err = errors.New("invoke failed")
+	err = invoke("failing")
+	if errors.Is(err, ErrCircuitOpen) || !mock.hasBeenCalled {
+		t.Fatal("Circuit should let a probe through once half-open", err)
+	}
+	err = invoke("failing")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("Failed probe should reopen the circuit", err)
+	}
+
+	now = now.Add(time.Minute)
+	mock.err = nil
+	err = invoke("failing")
+	if err != nil {
+		t.Fatal("Successful probe should be returned", err)
+	}
+	err = invoke("failing")
+	if err != nil || !mock.hasBeenCalled {
+		t.Fatal("Successful probe should close the circuit", err)
+	}
+}
@@ -3,33 +3,49 @@ package client
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
-type payload struct {
+// Payload is the API Gateway style event the client sends to a function. It
+// is not used to build requests, but DecodePayload returns one so tests can
+// make assertions on what a request sent.
+type Payload struct {
 	Headers               map[string]string `json:"headers"`
 	Path                  string            `json:"path"`
 	HttpMethod            string            `json:"httpMethod"`
-	QueryStringParameters map[string]string `json:"queryStringParameters"`
+	QueryStringParameters map[string]string `json:"queryStringParameters,omitempty"`
 	Body                  string            `json:"body"`
+	IsBase64Encoded       bool              `json:"isBase64Encoded,omitempty"`
 }
 
 type policy struct {
-	Rules map[string]bool `json:"rules"`
+	Rules map[string]interface{} `json:"rules"`
 }
 
 type responsePayload struct {
-	Body       string            `json:"body"`
-	StatusCode int               `json:"statusCode"`
-	Headers    map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
 }
 
 func toHeader(header map[string]string) http.Header {
@@ -41,10 +57,9 @@ func toHeader(header map[string]string) http.Header {
 }
 
 type responseBody struct {
-	Data   map[string]interface{} `json:"data"`
-	Errors []struct {
-		Message string `json:"message"`
-	} `json:"errors"`
+	Data       map[string]interface{} `json:"data"`
+	Errors     []GraphQLError         `json:"errors"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
 type Invoker interface {
@@ -53,41 +68,209 @@ type Invoker interface {
 
 type LambdaClient struct {
 	invoker Invoker
+	store   ObjectStore
 	account string
 	user    string
 	rules   map[string]bool
+
+	largePayloadBucket  string
+	tokenProvider       TokenProvider
+	invokeInputMutator  func(*lambda.InvokeInput)
+	clientContext       map[string]interface{}
+	closed              uint32
+	done                chan struct{}
+	httpClient          *http.Client
+	decompressResponses bool
+	identityFromContext IdentityResolver
+	maxQueryDepth       int
+	xrayTracing         bool
+	checkCredentials    bool
+	partialErrorHandler func([]GraphQLError)
+	invocationType      types.InvocationType
+	payloadSigner       PayloadSigner
+	gqlContentType      string
+	defaultVariables    map[string]interface{}
+	strictErrors        bool
+	pathRewriter        func(string) string
+	checkRequestIDs     bool
+	awsRetryer          aws.Retryer
+	coerceVariables     bool
+	connectTimeout      time.Duration
+	responseTimeout     time.Duration
+	base64Bodies        bool
+	headerAllowlist     map[string]bool
+	circuitBreaker      *circuitBreaker
+	richRules           map[string]interface{}
+	responseTransform   func([]byte) ([]byte, error)
+	clock               func() time.Time
+	after               func(time.Duration) <-chan time.Time
+	rand                io.Reader
+	httpCache           *httpCache
+	maxReportedErrors   int
+	functionOverride    func(string) string
+	invokeSlots         *semaphore
+	omitNullVariables   bool
+	idGenerator         func() string
+	jwt                 *jwtToken
+	requestTimeout      time.Duration
+	dryRun              *DryRunRecorder
+	headerNames         HeaderNames
+	responseValidator   func(*http.Response) error
+	deadlineHeaderName  string
+	deadlineFormat      DeadlineFormat
+	retryNonIdempotent  bool
+	responseEnvelopeKey string
+	dump                *dumper
+	dumpBodyLimit       int
+	omitContentType     bool
+	resultHook          func(interface{}) error
+	profile             string
+	region              string
+	logger              *log.Logger
+	awsConfig           *aws.Config
+}
+
+// logf writes a warning to the logger set with WithLogger, or to the
+// standard logger when none is set.
+func (c *LambdaClient) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// policyRules returns the boolean rules with any rules set through
+// WithPolicyRules on top.
+func (c *LambdaClient) policyRules() map[string]interface{} {
+	if c.rules == nil && c.richRules == nil {
+		return nil
+	}
+	rules := make(map[string]interface{}, len(c.rules)+len(c.richRules))
+	for k, v := range c.rules {
+		rules[k] = v
+	}
+	for k, v := range c.richRules {
+		rules[k] = v
+	}
+	return rules
 }
 
-func (c *LambdaClient) buildHeaders() map[string]string {
-	policy, _ := json.Marshal(&policy{
-		Rules: c.rules,
+func (c *LambdaClient) buildHeaders(ctx context.Context) (map[string]string, error) {
+	policy, err := json.Marshal(&policy{
+		Rules: c.policyRules(),
 	})
-	return map[string]string{
-		"LifeOmic-Account": c.account,
-		"LifeOmic-User":    c.user,
-		"content-type":     "application/json",
-		"LifeOmic-Policy":  string(policy),
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	account, user := c.account, c.user
+	if c.identityFromContext != nil {
+		if ctxAccount, ctxUser, ok := c.identityFromContext(ctx); ok {
+			account, user = ctxAccount, ctxUser
+		}
+	}
+	names := c.identityHeaders()
+	headers := map[string]string{
+		names.Account: account,
+		names.User:    user,
+		names.Policy:  string(policy),
+	}
+	if !c.omitContentType {
+		headers["content-type"] = "application/json"
 	}
+	if c.jwt != nil {
+		authorization, err := c.jwtAuthorization()
+		if err != nil {
+			return nil, err
+		}
+		headers["Authorization"] = authorization
+	}
+	if c.tokenProvider != nil && ctx.Value(estimatingKey{}) == nil {
+		token, err := c.tokenProvider(ctx)
+		if err != nil {
+			return nil, err
+		}
+		headers["Authorization"] = "Bearer " + token
+	}
+	if c.decompressResponses {
+		headers["Accept-Encoding"] = "gzip"
+	}
+	if traceID := traceIDFromContext(ctx); c.xrayTracing && traceID != "" {
+		headers[traceHeader] = traceID
+	}
+	if requestID := requestIDFromContext(ctx); requestID != "" {
+		headers[requestIDHeader] = requestID
+	}
+	c.mergeHeaders(headers, headersFromContext(ctx))
+	if deadline, ok := ctx.Deadline(); ok {
+		name, value := c.deadlineHeader(deadline)
+		headers[name] = value
+	}
+	return headers, nil
+}
+
+func (c *LambdaClient) buildGqlQuery(ctx context.Context, path string, query string, variables map[string]interface{}) ([]byte, error) {
+	return c.buildGqlRequest(ctx, path, GraphQLRequest{Query: query, Variables: variables})
 }
 
-func (c *LambdaClient) buildGqlQuery(path string, query string, variables map[string]interface{}) []byte {
-	type Body struct {
-		Query     string                 `json:"query"`
-		Variables map[string]interface{} `json:"variables"`
+func (c *LambdaClient) buildGqlRequest(ctx context.Context, path string, request GraphQLRequest) ([]byte, error) {
+	variables := c.withDefaultVariables(request.Variables)
+	if c.omitNullVariables {
+		variables = omitNullVariables(variables)
 	}
-	body, _ := json.Marshal(&Body{Query: query, Variables: variables})
-	payload := &payload{
-		Headers:               c.buildHeaders(),
-		HttpMethod:            "POST",
-		QueryStringParameters: map[string]string{},
-		Path:                  path,
-		Body:                  string(body),
+	if c.coerceVariables {
+		variables = coerceVariables(variables, c.logf)
 	}
-	bytes, err := json.Marshal(payload)
+	request.Variables = variables
+	buf := payloadBuffers.Get().(*bytes.Buffer)
+	defer payloadBuffers.Put(buf)
+	buf.Reset()
+	err := json.NewEncoder(buf).Encode(&request)
 	if err != nil {
-		log.Fatalf("Failed to marshall payload %v", err)
+		return nil, err
+	}
+	body := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	if c.gqlContentType != "" {
+		ctx = contextWithDefaultHeaders(ctx, map[string]string{"content-type": c.gqlContentType})
 	}
-	return bytes
+	headers, err := c.buildHeaders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = c.signBody(headers, body)
+	if err != nil {
+		return nil, err
+	}
+	return encodePayload(headers, "POST", path, body, false)
+}
+
+// withDefaultVariables returns variables on top of the client's default
+// variables, leaving both maps unchanged.
+func (c *LambdaClient) withDefaultVariables(variables map[string]interface{}) map[string]interface{} {
+	if len(c.defaultVariables) == 0 {
+		return variables
+	}
+	merged := make(map[string]interface{}, len(c.defaultVariables)+len(variables))
+	for k, v := range c.defaultVariables {
+		merged[k] = v
+	}
+	for k, v := range variables {
+		merged[k] = v
+	}
+	return merged
+}
+
+// signBody adds the header produced by the payload signer, if one is set.
+func (c *LambdaClient) signBody(headers map[string]string, body []byte) error {
+	if c.payloadSigner == nil {
+		return nil
+	}
+	name, value, err := c.payloadSigner(body)
+	if err != nil {
+		return err
+	}
+	headers[name] = value
+	return nil
 }
 
 func parseUri(uri string) (*string, *string, error) {
@@ -100,112 +283,694 @@ func parseUri(uri string) (*string, *string, error) {
 	return &functionName, &path, nil
 }
 
-func (c *LambdaClient) Gql(uri string, query string, variables map[string]interface{}) (*map[string]interface{}, error) {
+// resolveUri splits uri like parseUri and applies the function override and
+// path rewriter, if they are set.
+func (c *LambdaClient) resolveUri(uri string) (*string, *string, error) {
 	functionName, path, err := parseUri(uri)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	resp, err := c.invoker.Invoke(context.Background(), &lambda.InvokeInput{
-		FunctionName: functionName,
-		Payload:      c.buildGqlQuery(*path, query, variables),
-	})
+	if c.functionOverride != nil {
+		overridden := c.functionOverride(*functionName)
+		functionName = &overridden
+	}
+	if c.pathRewriter != nil {
+		rewritten := c.pathRewriter(*path)
+		path = &rewritten
+	}
+	return functionName, path, nil
+}
+
+// Lambda rejects a ClientContext larger than this once base64 encoded.
+const maxClientContextSize = 3583
+
+func encodeClientContext(clientContext map[string]interface{}) (string, error) {
+	data, err := json.Marshal(clientContext)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	if len(encoded) > maxClientContextSize {
+		return "", fmt.Errorf("%w: %d bytes", ErrClientContextTooLarge, len(encoded))
+	}
+	return encoded, nil
+}
+
+// The Invoke API answers each invocation type with its own status code.
+var expectedInvokeStatus = map[types.InvocationType]int32{
+	types.InvocationTypeRequestResponse: 200,
+	types.InvocationTypeEvent:           202,
+	types.InvocationTypeDryRun:          204,
+}
+
+type invokeOutput struct {
+	Payload    []byte
+	Duration   time.Duration
+	StatusCode int32
+	// BodyStatusCode is the statusCode of the decoded response payload.
+	BodyStatusCode int
+	QueueWait      time.Duration
+	RateLimit      *RateLimitInfo
+}
+
+// eventAccepted reports whether resp is the empty answer to an Event
+// invocation, which Lambda queued without running the function yet.
+func eventAccepted(resp *invokeOutput) bool {
+	return resp.StatusCode == http.StatusAccepted && len(bytes.TrimSpace(resp.Payload)) == 0
+}
 
+// checkInvokeOutput returns an error for invocations that did not produce a
+// response from the function.
+func checkInvokeOutput(input *lambda.InvokeInput, resp *lambda.InvokeOutput) error {
+	if resp.FunctionError != nil {
+		return fmt.Errorf("%w: %s: %q", ErrFunctionError, *resp.FunctionError, snippet(resp.Payload))
+	}
+	if expected, ok := expectedInvokeStatus[input.InvocationType]; ok && resp.StatusCode != expected {
+		return &invokeStatusError{invocationType: input.InvocationType, expected: expected, statusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+func (c *LambdaClient) invoke(ctx context.Context, functionName string, payload []byte) (*invokeOutput, error) {
+	return c.invokeAs(ctx, functionName, c.invocationType, payload)
+}
+
+// invokeAs invokes the function with the given invocation type, going
+// through the same guards as every other request: the closed check, the
+// invoke input mutator, the concurrency cap and the circuit breaker.
+func (c *LambdaClient) invokeAs(ctx context.Context, functionName string, invocationType types.InvocationType, payload []byte) (*invokeOutput, error) {
+	if atomic.LoadUint32(&c.closed) == 1 {
+		return nil, ErrClientClosed
+	}
+	if c.dryRun != nil {
+		return c.dryRun.record(functionName, invocationType, payload)
+	}
+	if c.largePayloadBucket != "" && c.store == nil {
+		return nil, ErrMissingObjectStore
+	}
+	if c.largePayloadBucket != "" && len(payload) > maxPayloadSize {
+		pointer, key, err := c.putLargePayload(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = pointer
+		if invocationType == "" || invocationType == types.InvocationTypeRequestResponse {
+			defer c.deleteLargePayload(ctx, key)
+		}
+	}
+
+	input := &lambda.InvokeInput{
+		FunctionName:   &functionName,
+		Payload:        payload,
+		InvocationType: invocationType,
+	}
+	if c.clientContext != nil {
+		clientContext, err := encodeClientContext(c.clientContext)
+		if err != nil {
+			return nil, err
+		}
+		input.ClientContext = &clientContext
+	}
+	if c.invokeInputMutator != nil {
+		c.invokeInputMutator(input)
+	}
+	var optFns []func(*lambda.Options)
+	if traceID := traceIDFromContext(ctx); c.xrayTracing && traceID != "" {
+		optFns = append(optFns, withTraceHeader(traceID))
+	}
+	if retriesDisabled(ctx) {
+		optFns = append(optFns, withoutRetries)
+	}
+	var queueWait time.Duration
+	if c.invokeSlots != nil {
+		queued := c.now()
+		waited, err := c.invokeSlots.acquire(ctx, c.done)
+		if err != nil {
+			return nil, err
+		}
+		if waited {
+			queueWait = c.now().Sub(queued)
+		}
+		defer c.invokeSlots.release()
+	}
+	if c.circuitBreaker != nil && !c.circuitBreaker.allow(functionName, c.now()) {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, functionName)
+	}
+	c.dumpRequest(functionName, input.Payload)
+	start := c.now()
+	resp, err := c.invoker.Invoke(ctx, input, optFns...)
+	duration := c.now().Sub(start)
+	if err == nil {
+		err = checkInvokeOutput(input, resp)
+	}
+	if c.circuitBreaker != nil {
+		if ctx.Err() != nil {
+			c.circuitBreaker.abandon(functionName)
+		} else {
+			c.circuitBreaker.record(functionName, err != nil, c.now())
+		}
+	}
 	if err != nil {
+		c.dumpResponse(functionName, 0, duration, nil, err)
+		// The SDK buries context errors in an OperationError; return them
+		// as is so callers can compare against context.Canceled.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, err
 	}
-	var payload responsePayload
-	err = json.Unmarshal(resp.Payload, &payload)
+
+	output := &invokeOutput{Payload: resp.Payload, Duration: duration, StatusCode: resp.StatusCode, QueueWait: queueWait}
+	if c.largePayloadBucket != "" {
+		output.Payload, err = c.resolveLargePayload(ctx, resp.Payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.dumpResponse(functionName, resp.StatusCode, duration, output.Payload, nil)
+	return output, nil
+}
+
+func (c *LambdaClient) Gql(uri string, query string, variables map[string]interface{}) (*map[string]interface{}, error) {
+	return c.GqlWithContext(context.Background(), uri, query, variables)
+}
+
+// GqlWithContext is like Gql but invokes the function with the given context.
+// When ctx has a deadline the remaining time is sent to the function in the
+// X-Timeout-Ms header so it can give up on work the caller will abandon.
+//
+// When the response holds both data and errors, the partial data is returned
+// along with the errors unless the client was built WithStrictErrors. A
+// single error is returned as a GraphQLError and several as GraphQLErrors.
+//
+// Queries using @defer or @stream are answered with the fully merged result.
+// Lambda can not stream a response, so nothing is returned until the last
+// incremental part has been delivered.
+func (c *LambdaClient) GqlWithContext(ctx context.Context, uri string, query string, variables map[string]interface{}) (*map[string]interface{}, error) {
+	resp, err := c.GqlWithMetadata(ctx, uri, query, variables)
+	if resp == nil {
+		return nil, err
+	}
+	return &resp.Data, err
+}
+
+// GqlResponse is the data of a GraphQL response along with details about
+// the request that produced it.
+type GqlResponse struct {
+	Data map[string]interface{}
+	// Duration is the time spent in the Lambda Invoke call.
+	Duration time.Duration
+	// QueueWait is the time spent waiting for a free slot under the cap set
+	// with WithMaxConcurrency, which is zero when the request did not wait.
+	QueueWait time.Duration
+	// Errors holds the GraphQL errors of a partial response, truncated when
+	// the client was built WithMaxReportedErrors.
+	Errors []GraphQLError
+	// OmittedErrors is the number of errors dropped from Errors.
+	OmittedErrors int
+	// InvokeStatusCode is the HTTP status of the Lambda Invoke API call and
+	// StatusCode the status the function put in its response.
+	InvokeStatusCode int
+	StatusCode       int
+	// Extensions is the top-level extensions map of the response, where
+	// gateways put notices such as deprecation warnings.
+	Extensions map[string]interface{}
+	// RateLimit is the rate limit state reported in the response headers,
+	// or nil when the response had none.
+	RateLimit *RateLimitInfo
+	// Accepted reports that Lambda queued the request of a client using
+	// Event invocations. The function has not run yet, so there is no data.
+	Accepted bool
+}
+
+// GqlWithMetadata is like GqlWithContext but also returns metadata about the
+// call alongside the data.
+func (c *LambdaClient) GqlWithMetadata(ctx context.Context, uri string, query string, variables map[string]interface{}) (*GqlResponse, error) {
+	prepared, err := c.Prepare(uri, query)
 	if err != nil {
 		return nil, err
 	}
+	return c.gqlResponse(ctx, prepared, variables)
+}
 
-	var body responseBody
-	err = json.Unmarshal([]byte(payload.Body), &body)
+func (c *LambdaClient) gqlResponse(ctx context.Context, prepared *PreparedQuery, variables map[string]interface{}) (*GqlResponse, error) {
+	raw, resp, err := c.gqlBody(ctx, prepared, variables)
 	if err != nil {
 		return nil, err
 	}
+	if eventAccepted(resp) {
+		return &GqlResponse{
+			Accepted:         true,
+			Duration:         resp.Duration,
+			QueueWait:        resp.QueueWait,
+			InvokeStatusCode: int(resp.StatusCode),
+		}, nil
+	}
+	var body responseBody
+	err = json.Unmarshal(raw, &body)
+	if err != nil {
+		return nil, decodeError(ErrDecodeBody, err, raw)
+	}
 	if len(body.Errors) > 0 {
-		return nil, errors.New(body.Errors[0].Message)
+		errs, omitted := c.limitErrors(body.Errors)
+		err = joinGraphQLErrors(errs)
+		if omitted > 0 {
+			err = fmt.Errorf("%w (%d of %d errors omitted)", err, omitted, len(body.Errors))
+		}
+		if body.Data == nil {
+			return nil, err
+		}
+		if c.partialErrorHandler != nil {
+			c.partialErrorHandler(errs)
+		}
+		if c.strictErrors {
+			return nil, err
+		}
+		return &GqlResponse{
+			Data:             body.Data,
+			Duration:         resp.Duration,
+			QueueWait:        resp.QueueWait,
+			Errors:           errs,
+			OmittedErrors:    omitted,
+			InvokeStatusCode: int(resp.StatusCode),
+			StatusCode:       resp.BodyStatusCode,
+			Extensions:       body.Extensions,
+			RateLimit:        resp.RateLimit,
+		}, err
 	}
-	return &body.Data, nil
+	return &GqlResponse{
+		Data:             body.Data,
+		Duration:         resp.Duration,
+		QueueWait:        resp.QueueWait,
+		InvokeStatusCode: int(resp.StatusCode),
+		StatusCode:       resp.BodyStatusCode,
+		Extensions:       body.Extensions,
+		RateLimit:        resp.RateLimit,
+	}, nil
 }
 
+// GqlRawData is like GqlWithContext but returns the data of the response as
+// undecoded JSON, along with any GraphQL errors, so it can be forwarded
+// without a round trip through map[string]interface{}. The returned error is
+// only set when the request itself fails. The errors are truncated when the
+// client was built WithMaxReportedErrors. Requests accepted as Event
+// invocations return nil data, where a response without data holds null.
+func (c *LambdaClient) GqlRawData(ctx context.Context, uri string, query string, variables map[string]interface{}) (json.RawMessage, []GraphQLError, error) {
+	prepared, err := c.Prepare(uri, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, resp, err := c.gqlBody(ctx, prepared, variables)
+	if err != nil || eventAccepted(resp) {
+		return nil, nil, err
+	}
+	var body struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []GraphQLError  `json:"errors"`
+	}
+	err = json.Unmarshal(raw, &body)
+	if err != nil {
+		return nil, nil, decodeError(ErrDecodeBody, err, raw)
+	}
+	errs, _ := c.limitErrors(body.Errors)
+	return body.Data, errs, nil
+}
+
+// gqlBody sends a prepared GraphQL request and returns the decoded body of
+// the response, which is nil when the request was accepted as an Event
+// invocation.
+func (c *LambdaClient) gqlBody(ctx context.Context, prepared *PreparedQuery, variables map[string]interface{}) ([]byte, *invokeOutput, error) {
+	if c.requestTimeout <= 0 {
+		return c.sendGql(ctx, prepared, variables)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.requestTimeout)
+	defer cancel()
+	raw, resp, err := c.sendGql(timeoutCtx, prepared, variables)
+	return raw, resp, c.timeoutError(ctx, err)
+}
+
+func (c *LambdaClient) sendGql(ctx context.Context, prepared *PreparedQuery, variables map[string]interface{}) ([]byte, *invokeOutput, error) {
+	ctx, err := c.traceContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err = c.requestIDContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := c.buildGqlRequest(ctx, prepared.path, prepared.request(variables))
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.invoke(ctx, prepared.functionName, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if eventAccepted(resp) {
+		return nil, resp, nil
+	}
+	var payload responsePayload
+	err = json.Unmarshal(resp.Payload, &payload)
+	if err != nil {
+		return nil, nil, decodeError(ErrDecodeEnvelope, err, resp.Payload)
+	}
+	c.checkRequestID(ctx, &payload)
+	resp.BodyStatusCode = payload.StatusCode
+	resp.RateLimit = parseRateLimit(payload.Headers, c.now())
+
+	raw, _, err := c.decodeBody(&payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err = mergeIncremental(payload.Headers, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.responseEnvelopeKey != "" {
+		raw, err = unwrapEnvelope(raw, c.responseEnvelopeKey)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.responseTransform != nil {
+		raw, err = c.responseTransform(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return raw, resp, nil
+}
+
+// Subscribe always returns ErrSubscriptionsUnsupported. GraphQL subscriptions
+// need a long-lived connection, which a RequestResponse Lambda invocation
+// can not provide; poll with GqlWithContext instead.
+func (c *LambdaClient) Subscribe(ctx context.Context, uri string, query string, variables map[string]interface{}) error {
+	return ErrSubscriptionsUnsupported
+}
+
+// Do sends req to the function named by its URL, such as
+// "some-service:deployed/resource", as an API Gateway style event, and
+// returns the function's response whatever its status code. The headers of
+// req replace the client's headers of the same name, compared
+// case-insensitively, except for the identity headers and an Authorization
+// header set by WithJWT or WithTokenProvider, which can not be overridden.
+// Earlier versions never let request headers replace the client's headers.
 func (c *LambdaClient) Do(req *http.Request) (*http.Response, error) {
-	functionName, path, err := parseUri(req.URL.String())
+	if c.requestTimeout <= 0 {
+		return c.do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.requestTimeout)
+	defer cancel()
+	resp, err := c.do(req.WithContext(ctx))
+	if resp != nil {
+		resp.Request = req
+	}
+	return resp, c.timeoutError(req.Context(), err)
+}
+
+func (c *LambdaClient) do(req *http.Request) (*http.Response, error) {
+	functionName, path, err := c.resolveUri(req.URL.String())
+	if err != nil {
+		return nil, err
+	}
+	ctx := req.Context()
+	if !c.retryable(req) {
+		ctx = ContextWithoutRetries(ctx)
+	}
+	ctx, err = c.traceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = c.requestIDContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Copy additional headers from the req struct into lambda request headers
 	// go http.Header type doesn't align with the lambda header type
-	// so we just take the first value of the request header
-	headers := c.buildHeaders()
+	// so we just take the first value of the request header, skipping
+	// headers without one
+	headers, err := c.buildHeaders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reqHeaders := make(map[string]string, len(req.Header))
 	for k, v := range req.Header {
-		if _, ok := headers[k]; !ok {
-			headers[k] = v[0]
+		if len(v) == 0 || (c.headerAllowlist != nil && !c.headerAllowlist[http.CanonicalHeaderKey(k)]) {
+			continue
 		}
+		reqHeaders[k] = v[0]
 	}
+	c.mergeHeaders(headers, reqHeaders)
 
-	body, err := ioutil.ReadAll(req.Body)
+	// Conditional requests made by the caller are passed through untouched.
+	var cacheKey string
+	var cached *cachedResponse
+	if _, conditional := headerValue(headers, "If-None-Match"); c.httpCache != nil && req.Method == http.MethodGet && !conditional {
+		cacheKey = httpCacheKey(*functionName, *path, c.identityHeaders(), headers)
+		cached = c.httpCache.get(cacheKey)
+		if cached != nil {
+			headers["If-None-Match"] = cached.etag
+		}
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = c.signBody(headers, body)
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := json.Marshal(payload{
-		Headers:               headers,
-		HttpMethod:            req.Method,
-		QueryStringParameters: map[string]string{},
-		Path:                  *path,
-		Body:                  string(body),
-	})
+	if c.base64Bodies {
+		encoded := make([]byte, base64.StdEncoding.EncodedLen(len(body)))
+		base64.StdEncoding.Encode(encoded, body)
+		body = encoded
+	} else if !utf8.Valid(body) {
+		return nil, ErrInvalidUTF8Body
+	}
+	data, err := encodePayload(headers, req.Method, *path, body, c.base64Bodies)
 	if err != nil {
 		return nil, err
 	}
 
-	lambdaResponse, err := c.invoker.Invoke(req.Context(), &lambda.InvokeInput{
-		FunctionName: functionName,
-		Payload:      data,
-	})
-
+	lambdaResponse, err := c.invoke(ctx, *functionName, data)
 	if err != nil {
 		return nil, err
 	}
 
 	// attempt to convert lambda response into http Response
 	var respPayload responsePayload
-	err = json.Unmarshal(lambdaResponse.Payload, &respPayload)
+	if eventAccepted(lambdaResponse) {
+		// Event invocations are answered before the function runs, so
+		// there is no response to convert.
+		respPayload.StatusCode = http.StatusAccepted
+	} else {
+		err = json.Unmarshal(lambdaResponse.Payload, &respPayload)
+		if err != nil {
+			return nil, decodeError(ErrDecodeEnvelope, err, lambdaResponse.Payload)
+		}
+	}
+	c.checkRequestID(ctx, &respPayload)
+
+	respBody, decompressed, err := c.decodeBody(&respPayload)
 	if err != nil {
 		return nil, err
 	}
+	if cacheKey != "" {
+		if respPayload.StatusCode == http.StatusNotModified && cached != nil {
+			respPayload.StatusCode = http.StatusOK
+			respPayload.Headers = cached.headers
+			respBody, decompressed = cached.body, cached.decompressed
+		} else if respPayload.StatusCode == http.StatusOK {
+			c.httpCache.store(cacheKey, respPayload.Headers, respBody, decompressed)
+		}
+	}
 
+	// Like http.Client, any response the function produced is returned
+	// without an error regardless of its status code.
 	resp := http.Response{
-		Body:       ioutil.NopCloser(bytes.NewBufferString(respPayload.Body)),
-		StatusCode: respPayload.StatusCode,
-		Header:     toHeader(respPayload.Headers),
+		Status:        fmt.Sprintf("%d %s", respPayload.StatusCode, http.StatusText(respPayload.StatusCode)),
+		StatusCode:    respPayload.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        toHeader(respPayload.Headers),
+		Body:          ioutil.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+		Uncompressed:  decompressed,
+	}
+	if decompressed {
+		for k := range resp.Header {
+			if strings.EqualFold(k, "Content-Encoding") {
+				delete(resp.Header, k)
+			}
+		}
+	}
+	if c.responseValidator != nil {
+		err = c.responseValidator(&resp)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid response: %w", err)
+		}
 	}
 
 	return &resp, nil
 }
 
-func (c *LambdaClient) AppStore() AppStoreClient {
+func (c *LambdaClient) AppStore(opts ...ServiceOption) AppStoreClient {
 	return AppStoreClient{
 		client:     c,
 		graphqlUrl: "app-store-service:deployed/graphql",
+		headers:    buildServiceOptions(opts).headers,
 	}
 }
 
-func (c *LambdaClient) Marketplace() MarketplaceClient {
+func (c *LambdaClient) Marketplace(opts ...ServiceOption) MarketplaceClient {
 	return MarketplaceClient{
 		client:     c,
 		graphqlUrl: "marketplace-service:deployed/v1/marketplace/authenticated/graphql",
+		headers:    buildServiceOptions(opts).headers,
+	}
+}
+
+func (c *LambdaClient) DataService(opts ...ServiceOption) DataServiceClient {
+	return DataServiceClient{
+		client:     c,
+		graphqlUrl: "data-service:deployed/graphql",
+		headers:    buildServiceOptions(opts).headers,
+	}
+}
+
+// FHIR returns a client for the FHIR service of the client's account. Its
+// requests fail with ErrMissingAccount when the client has no account.
+func (c *LambdaClient) FHIR(opts ...ServiceOption) FHIRClient {
+	return FHIRClient{
+		client:  c,
+		baseUrl: "fhir-service:deployed",
+		account: c.account,
+		headers: buildServiceOptions(opts).headers,
+	}
+}
+
+// Close stops the client from making requests. Requests made after Close
+// return ErrClientClosed, as do requests still waiting for a
+// WithMaxConcurrency slot, and Watch stops polling. Requests already sent
+// are left to finish. The HTTP cache, circuit breaker and concurrency cap
+// may be shared with clones, so Close leaves them alone; they are freed with
+// the last client using them. Calling Close more than once is a no-op.
+func (c *LambdaClient) Close() error {
+	if atomic.CompareAndSwapUint32(&c.closed, 0, 1) && c.done != nil {
+		close(c.done)
+	}
+	return nil
+}
+
+// Clone returns a copy of the client with opts applied, sharing its AWS
+// clients and configuration. It is a cheap alternative to BuildClient for
+// deriving request-scoped clients, for example with WithAccount, WithUser
+// and WithRules. The rules, client context and default variables are copied
+// so changes to the clone's maps do not affect c. The clone is open even
+// if c has been closed.
+//
+// Some state is deliberately shared with c, because it describes the
+// functions being called rather than the caller:
+//   - the circuit breaker and the WithMaxConcurrency cap, so requests made
+//     through every clone count against the same limits;
+//   - the HTTP cache, whose entries are keyed by identity, so a clone with
+//     another account or user never sees c's responses;
+//   - the DryRunRecorder and WithDump writer, so one test or debug session
+//     sees the requests of every clone.
+//
+// Passing WithCircuitBreaker, WithMaxConcurrency, WithHTTPCache,
+// WithDryRunRecorder or WithDump to Clone gives the clone its own instead.
+// A clone given WithLargePayloadBucket by a client without one gets an S3
+// client from the AWS configuration BuildClient loaded.
+func (c *LambdaClient) Clone(opts ...Option) *LambdaClient {
+	clone := *c
+	clone.closed = 0
+	clone.done = make(chan struct{})
+	clone.rules = copyMap(c.rules)
+	clone.clientContext = copyMap(c.clientContext)
+	clone.defaultVariables = copyMap(c.defaultVariables)
+	clone.richRules = copyMap(c.richRules)
+	for _, opt := range opts {
+		opt(&clone)
+	}
+	if clone.largePayloadBucket != "" && clone.store == nil && clone.awsConfig != nil {
+		clone.store = s3.NewFromConfig(*clone.awsConfig)
+	}
+	return &clone
+}
+
+func copyMap[V any](m map[string]V) map[string]V {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]V, len(m))
+	for k, v := range m {
+		copied[k] = v
 	}
+	return copied
+}
+
+// buildHTTPClient returns the AWS SDK's default HTTP client with the
+// connect and response timeouts applied.
+func (c *LambdaClient) buildHTTPClient() *awshttp.BuildableClient {
+	return awshttp.NewBuildableClient().
+		WithDialerOptions(func(d *net.Dialer) {
+			if c.connectTimeout > 0 {
+				d.Timeout = c.connectTimeout
+			}
+		}).
+		WithTransportOptions(func(tr *http.Transport) {
+			if c.responseTimeout > 0 {
+				tr.ResponseHeaderTimeout = c.responseTimeout
+			}
+		})
 }
 
-func BuildClient(account string, user string, rules map[string]bool) (*LambdaClient, error) {
-	cfg, err := config.LoadDefaultConfig(context.Background())
+func BuildClient(account string, user string, rules map[string]bool, opts ...Option) (*LambdaClient, error) {
+	client := LambdaClient{user: user, rules: rules, account: account, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(&client)
+	}
+	var loadOpts []func(*config.LoadOptions) error
+	if client.profile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(client.profile))
+	}
+	if client.region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(client.region))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), loadOpts...)
 	if err != nil {
 		return nil, err
 	}
-	client := LambdaClient{invoker: lambda.NewFromConfig(cfg), user: user, rules: rules, account: account}
+	if client.httpClient != nil {
+		cfg.HTTPClient = client.httpClient
+	} else if client.connectTimeout > 0 || client.responseTimeout > 0 {
+		cfg.HTTPClient = client.buildHTTPClient()
+	}
+	if client.awsRetryer != nil {
+		cfg.Retryer = func() aws.Retryer { return client.awsRetryer }
+	}
+	if client.checkCredentials {
+		if cfg.Credentials == nil {
+			return nil, errors.New("Unable to resolve AWS credentials: no credentials provider configured")
+		}
+		_, err = cfg.Credentials.Retrieve(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("Unable to resolve AWS credentials: %w", err)
+		}
+	}
+	client.invoker = lambda.NewFromConfig(cfg)
+	client.awsConfig = &cfg
+	if client.largePayloadBucket != "" && client.store == nil {
+		client.store = s3.NewFromConfig(cfg)
+	}
 	return &client, nil
 }
@@ -3,13 +3,23 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 )
 
 const MOCK_MUTATION = `
@@ -26,9 +36,12 @@ func TestBuildGqlQuery(t *testing.T) {
 			"testRule": true,
 		},
 	}
-	raw := client.buildGqlQuery("/some/path", MOCK_MUTATION, map[string]interface{}{"var": "value"})
+	raw, err := client.buildGqlQuery(context.Background(), "/some/path", MOCK_MUTATION, map[string]interface{}{"var": "value"})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
 	var parsed map[string]interface{}
-	err := json.Unmarshal(raw, &parsed)
+	err = json.Unmarshal(raw, &parsed)
 	if err != nil {
 		t.Fatal("Could not parse payload as json", string(raw))
 	}
@@ -182,3 +195,745 @@ func TestDo(t *testing.T) {
 	}
 
 }
+
+func payloadHeaders(t *testing.T, mock *MockInvoker) map[string]string {
+	var sent Payload
+	err := json.Unmarshal(mock.payload.Payload, &sent)
+	if err != nil {
+		t.Fatal("Could not parse payload as json", string(mock.payload.Payload))
+	}
+	return sent.Headers
+}
+
+func TestTokenProvider(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	calls := 0
+	client := LambdaClient{
+		invoker: &mock,
+		tokenProvider: func(ctx context.Context) (string, error) {
+			calls++
+			return fmt.Sprintf("token-%d", calls), nil
+		},
+	}
+
+	for i := 1; i <= 2; i++ {
+		_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+		if err != nil {
+			t.Fatal("Unexpected error", err)
+		}
+		auth := payloadHeaders(t, &mock)["Authorization"]
+		if auth != fmt.Sprintf("Bearer token-%d", i) {
+			t.Fatal("Did not send a fresh token", auth)
+		}
+	}
+
+	client.tokenProvider = func(ctx context.Context) (string, error) {
+		return "", errors.New("no token")
+	}
+	mock.hasBeenCalled = false
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || err.Error() != "no token" {
+		t.Fatal("Expected the token provider error", err)
+	}
+	if mock.hasBeenCalled {
+		t.Fatal("Should not invoke without a token")
+	}
+}
+
+func TestInvokeInputMutator(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	qualifier := "canary"
+	client := LambdaClient{
+		invoker: &mock,
+		invokeInputMutator: func(input *lambda.InvokeInput) {
+			if *input.FunctionName != "some_lambda:status" {
+				t.Fatal("Function name should be set before the mutator runs", *input.FunctionName)
+			}
+			input.Qualifier = &qualifier
+		},
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if mock.payload.Qualifier == nil || *mock.payload.Qualifier != "canary" {
+		t.Fatal("Mutator changes were not sent", mock.payload.Qualifier)
+	}
+}
+
+func TestClientContext(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := LambdaClient{
+		invoker:       &mock,
+		clientContext: map[string]interface{}{"custom": map[string]interface{}{"flag": true}},
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(*mock.payload.ClientContext)
+	if err != nil {
+		t.Fatal("Client context is not base64", *mock.payload.ClientContext)
+	}
+	if string(decoded) != "{\"custom\":{\"flag\":true}}" {
+		t.Fatal("Did not send client context", string(decoded))
+	}
+
+	client.clientContext = map[string]interface{}{"big": strings.Repeat("a", maxClientContextSize)}
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrClientContextTooLarge) {
+		t.Fatal("Expected client context size error", err)
+	}
+}
+
+func TestDeadlineHeader(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if timeout, ok := payloadHeaders(t, &mock)["X-Timeout-Ms"]; ok {
+		t.Fatal("Should not send a timeout without a deadline", timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err = client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	timeout, err := strconv.Atoi(payloadHeaders(t, &mock)["X-Timeout-Ms"])
+	if err != nil {
+		t.Fatal("Expected a numeric timeout header", err)
+	}
+	if timeout <= 0 || timeout > 60000 {
+		t.Fatal("Timeout header does not match deadline", timeout)
+	}
+}
+
+func TestClose(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	err := client.Close()
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if err = client.Close(); err != nil {
+		t.Fatal("Closing twice should not fail", err)
+	}
+
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrClientClosed) {
+		t.Fatal("Expected closed client error", err)
+	}
+	if mock.hasBeenCalled {
+		t.Fatal("Closed client should not invoke")
+	}
+
+	queued := client.Clone(WithMaxConcurrency(1))
+	queued.invokeSlots.acquire(context.Background(), nil)
+	done := make(chan error)
+	go func() {
+		_, err := queued.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+		done <- err
+	}()
+	if !waitForWaiters(queued.invokeSlots, 1) {
+		t.Fatal("Timed out waiting for the queued request")
+	}
+	queued.Close()
+	select {
+	case err = <-done:
+		if !errors.Is(err, ErrClientClosed) {
+			t.Fatal("Expected queued requests to fail with the closed client error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not release the queued request")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	mock := MockInvoker{}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+	err := client.Subscribe(context.Background(), "some_lambda:status/some/path", "subscription { updates }", nil)
+	if !errors.Is(err, ErrSubscriptionsUnsupported) {
+		t.Fatal("Expected subscriptions to be unsupported", err)
+	}
+	if mock.hasBeenCalled {
+		t.Fatal("Subscribe should not invoke")
+	}
+}
+
+func TestDoStatusSemantics(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"statusCode\": 404, \"body\": \"not found\" }"),
+		},
+	}
+	client := &LambdaClient{
+		invoker: &mock,
+	}
+	newRequest := func() *http.Request {
+		req, err := http.NewRequest("GET", "some-service:deployed/resource", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	resp, err := client.Do(newRequest())
+	if err != nil {
+		t.Fatal("Application errors should not return an error", err)
+	}
+	if resp.StatusCode != 404 || resp.Status != "404 Not Found" {
+		t.Fatal("Did not return application status", resp.Status)
+	}
+
+	mock.response = nil
+	mock.err = errors.New("invoke failed")
+	resp, err = client.Do(newRequest())
+	if err == nil || err.Error() != "invoke failed" {
+		t.Fatal("Expected invoke error", err)
+	}
+	if resp != nil {
+		t.Fatal("Response should be nil on invoke failure", resp)
+	}
+
+	functionError := "Unhandled"
+	mock.err = nil
+	mock.response = &lambda.InvokeOutput{
+		FunctionError: &functionError,
+		Payload:       []byte("{ \"errorMessage\": \"boom\" }"),
+	}
+	resp, err = client.Do(newRequest())
+	if !errors.Is(err, ErrFunctionError) {
+		t.Fatal("Expected function error", err)
+	}
+	if resp != nil {
+		t.Fatal("Response should be nil on function error", resp)
+	}
+}
+
+type slowInvoker struct {
+	MockInvoker
+	delay time.Duration
+}
+
+func (s *slowInvoker) Invoke(ctx context.Context, payload *lambda.InvokeInput, rest ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	time.Sleep(s.delay)
+	return s.MockInvoker.Invoke(ctx, payload, rest...)
+}
+
+func TestGqlWithMetadataDuration(t *testing.T) {
+	invoker := slowInvoker{
+		MockInvoker: MockInvoker{
+			response: &lambda.InvokeOutput{
+				Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+			},
+		},
+		delay: 20 * time.Millisecond,
+	}
+	client := LambdaClient{
+		invoker: &invoker,
+	}
+
+	resp, err := client.GqlWithMetadata(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if !resp.Data["result"].(bool) {
+		t.Fatal("Did not return data", resp.Data)
+	}
+	if resp.Duration < invoker.delay {
+		t.Fatal("Duration should cover the invoke call", resp.Duration)
+	}
+}
+
+func TestGqlWithMetadataExtensions(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": { "data": { "result": true }, "extensions": { "warnings": [{ "message": "result is deprecated" }] } } }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	resp, err := client.GqlWithMetadata(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	warnings, _ := resp.Extensions["warnings"].([]interface{})
+	if len(warnings) != 1 || warnings[0].(map[string]interface{})["message"] != "result is deprecated" {
+		t.Fatal("Did not return the response extensions", resp.Extensions)
+	}
+}
+
+func TestInvocationType(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			StatusCode: 202,
+		},
+	}
+	client := LambdaClient{
+		invoker:        &mock,
+		invocationType: types.InvocationTypeEvent,
+	}
+
+	_, err := client.invoke(context.Background(), "some_lambda", []byte("{}"))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if mock.payload.InvocationType != types.InvocationTypeEvent {
+		t.Fatal("Did not use invocation type", mock.payload.InvocationType)
+	}
+
+	mock.response.StatusCode = 200
+	_, err = client.invoke(context.Background(), "some_lambda", []byte("{}"))
+	if !errors.Is(err, ErrUnexpectedInvokeStatus) {
+		t.Fatal("Expected status mismatch error", err)
+	}
+}
+
+func TestEventInvocationAccepted(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			StatusCode: 202,
+		},
+	}
+	client := LambdaClient{
+		invoker:        &mock,
+		invocationType: types.InvocationTypeEvent,
+	}
+
+	res, err := client.GqlWithMetadata(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil || !res.Accepted || res.Data != nil || res.InvokeStatusCode != 202 {
+		t.Fatal("Expected the event to be reported as accepted", res, err)
+	}
+	data, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil || len(*data) != 0 {
+		t.Fatal("Expected no data and no error", data, err)
+	}
+	executed, err := client.Execute(context.Background(), "some_lambda:status/some/path", GraphQLRequest{Query: MOCK_MUTATION})
+	if err != nil || !executed.Accepted {
+		t.Fatal("Expected Execute to report the event as accepted", executed, err)
+	}
+	raw, errs, err := client.GqlRawData(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, nil)
+	if err != nil || raw != nil || errs != nil {
+		t.Fatal("Expected no raw data", string(raw), errs, err)
+	}
+
+	req, _ := http.NewRequest("POST", "some-service:deployed/resource", bytes.NewBufferString("resource body"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusAccepted || len(body) != 0 {
+		t.Fatal("Expected an empty 202 response", resp.StatusCode, string(body))
+	}
+}
+
+func TestResponseValidator(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "statusCode": 200, "headers": { "Content-Type": "application/json" }, "body": "{}" }`),
+		},
+	}
+	errMissingVersion := errors.New("missing Api-Version header")
+	client := LambdaClient{
+		invoker: &mock,
+	}
+	WithResponseValidator(func(resp *http.Response) error {
+		if resp.Header.Get("Api-Version") == "" {
+			return errMissingVersion
+		}
+		return nil
+	})(&client)
+
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+	resp, err := client.Do(req)
+	if !errors.Is(err, errMissingVersion) || resp != nil {
+		t.Fatal("Expected the validation error", resp, err)
+	}
+
+	mock.response.Payload = []byte(`{ "statusCode": 200, "headers": { "Api-Version": "2" }, "body": "{}" }`)
+	resp, err = client.Do(req)
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatal("Expected a valid response", resp, err)
+	}
+}
+
+func TestPayloadSigner(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	key := []byte("secret")
+	sign := func(body []byte) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	client := &LambdaClient{
+		invoker: &mock,
+		payloadSigner: func(body []byte) (string, string, error) {
+			return "X-Signature", sign(body), nil
+		},
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{"var": "value"})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	var sent Payload
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Headers["X-Signature"] != sign([]byte(sent.Body)) {
+		t.Fatal("Signature does not match the sent body", sent.Headers)
+	}
+
+	req, _ := http.NewRequest("PUT", "some-service:deployed/resource", bytes.NewBufferString("resource body"))
+	_, err = client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Headers["X-Signature"] != sign([]byte("resource body")) {
+		t.Fatal("Do did not sign the body", sent.Headers)
+	}
+
+	client.payloadSigner = func(body []byte) (string, string, error) {
+		return "", "", errors.New("signing failed")
+	}
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || err.Error() != "signing failed" {
+		t.Fatal("Expected signer error", err)
+	}
+}
+
+func TestDefaultVariables(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+		defaultVariables: map[string]interface{}{
+			"project": "default_project",
+			"dataset": "default_dataset",
+		},
+	}
+
+	vars := map[string]interface{}{"dataset": "some_dataset"}
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, vars)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	var sent Payload
+	json.Unmarshal(mock.payload.Payload, &sent)
+	var body struct {
+		Variables map[string]interface{}
+	}
+	json.Unmarshal([]byte(sent.Body), &body)
+	if body.Variables["project"] != "default_project" {
+		t.Fatal("Did not send default variable", body.Variables)
+	}
+	if body.Variables["dataset"] != "some_dataset" {
+		t.Fatal("Call variable should override the default", body.Variables)
+	}
+	if len(vars) != 1 || len(client.defaultVariables) != 2 {
+		t.Fatal("Merging should not modify the given maps", vars, client.defaultVariables)
+	}
+}
+
+func TestPathRewriter(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+		pathRewriter: func(path string) string {
+			return "/tenant" + path
+		},
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	var sent Payload
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Path != "/tenant/some/path" {
+		t.Fatal("Gql did not rewrite the path", sent.Path)
+	}
+	if *mock.payload.FunctionName != "some_lambda:status" {
+		t.Fatal("Function name should not be rewritten", *mock.payload.FunctionName)
+	}
+
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+	_, err = client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Path != "/tenant/resource" {
+		t.Fatal("Do did not rewrite the path", sent.Path)
+	}
+}
+
+func TestFunctionOverride(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+	WithFunctionOverride(func(functionName string) string {
+		return strings.Replace(functionName, ":", "-canary:", 1)
+	})(&client)
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if *mock.payload.FunctionName != "some_lambda-canary:status" {
+		t.Fatal("Gql did not remap the function", *mock.payload.FunctionName)
+	}
+	var sent Payload
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Path != "/some/path" {
+		t.Fatal("Path should not be changed", sent.Path)
+	}
+
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+	_, err = client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if *mock.payload.FunctionName != "some-service-canary:deployed" {
+		t.Fatal("Do did not remap the function", *mock.payload.FunctionName)
+	}
+}
+
+func TestClone(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	base := &LambdaClient{
+		invoker: &mock,
+		account: "base_account",
+		user:    "base_user",
+		rules:   map[string]bool{"baseRule": true},
+	}
+	base.Close()
+
+	clone := base.Clone(WithAccount("other_account"), WithUser("other_user"))
+	clone.rules["cloneRule"] = true
+
+	_, err := clone.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	var sent Payload
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Headers["LifeOmic-Account"] != "other_account" || sent.Headers["LifeOmic-User"] != "other_user" {
+		t.Fatal("Clone did not apply overrides", sent.Headers)
+	}
+	if !strings.Contains(sent.Headers["LifeOmic-Policy"], "baseRule") {
+		t.Fatal("Clone did not keep base rules", sent.Headers)
+	}
+	if base.account != "base_account" || base.rules["cloneRule"] {
+		t.Fatal("Clone should not modify the base client", base)
+	}
+}
+
+func TestCloneSharedState(t *testing.T) {
+	base := (&LambdaClient{}).Clone(
+		WithHTTPCache(0),
+		WithCircuitBreaker(CircuitBreakerSettings{FailureThreshold: 1}),
+		WithMaxConcurrency(2),
+	)
+
+	clone := base.Clone(WithAccount("other_account"))
+	if clone.httpCache != base.httpCache || clone.circuitBreaker != base.circuitBreaker || clone.invokeSlots != base.invokeSlots {
+		t.Fatal("Expected the cache, breaker and concurrency cap to be shared")
+	}
+
+	own := base.Clone(WithHTTPCache(0), WithCircuitBreaker(CircuitBreakerSettings{FailureThreshold: 1}), WithMaxConcurrency(2))
+	if own.httpCache == base.httpCache || own.circuitBreaker == base.circuitBreaker || own.invokeSlots == base.invokeSlots {
+		t.Fatal("Expected the options to give the clone its own state")
+	}
+}
+
+func TestGqlRawData(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": {\\\"count\\\":12345678901234567890}, \\\"errors\\\": [{ \\\"message\\\": \\\"partial\\\" }] }\" }"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	data, errs, err := client.GqlRawData(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if string(data) != `{"count":12345678901234567890}` {
+		t.Fatal("Data should be returned untouched", string(data))
+	}
+	if len(errs) != 1 || errs[0].Message != "partial" {
+		t.Fatal("Did not return GraphQL errors", errs)
+	}
+}
+
+func TestDoBodyEncoding(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"statusCode\": 200, \"body\": \"\" }"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+	binary := []byte{0xff, 0xfe, 'a'}
+
+	req, _ := http.NewRequest("PUT", "some-service:deployed/resource", bytes.NewReader(binary))
+	_, err := client.Do(req)
+	if !errors.Is(err, ErrInvalidUTF8Body) {
+		t.Fatal("Expected invalid UTF-8 error", err)
+	}
+	if mock.hasBeenCalled {
+		t.Fatal("Invalid body should not be sent")
+	}
+
+	client.base64Bodies = true
+	req, _ = http.NewRequest("PUT", "some-service:deployed/resource", bytes.NewReader(binary))
+	_, err = client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	var sent Payload
+	json.Unmarshal(mock.payload.Payload, &sent)
+	decoded, _ := base64.StdEncoding.DecodeString(sent.Body)
+	if !sent.IsBase64Encoded || !bytes.Equal(decoded, binary) {
+		t.Fatal("Did not send base64 encoded body", sent)
+	}
+}
+
+func TestHeaderAllowlist(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"statusCode\": 200, \"body\": \"\" }"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+		account: "some_account",
+	}
+	WithHeaderAllowlist([]string{"x-allowed"})(&client)
+
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+	req.Header.Set("X-Allowed", "yes")
+	req.Header.Set("Cookie", "secret")
+	_, err := client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	var sent Payload
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Headers["X-Allowed"] != "yes" {
+		t.Fatal("Allowed header was not forwarded", sent.Headers)
+	}
+	if _, ok := sent.Headers["Cookie"]; ok {
+		t.Fatal("Disallowed header was forwarded", sent.Headers)
+	}
+	if sent.Headers["LifeOmic-Account"] != "some_account" {
+		t.Fatal("Built-in headers should always be sent", sent.Headers)
+	}
+}
+
+func TestPolicyRules(t *testing.T) {
+	client := LambdaClient{
+		rules: map[string]bool{"readData": true, "writeData": true},
+	}
+	headers, err := client.buildHeaders(context.Background())
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if headers["LifeOmic-Policy"] != `{"rules":{"readData":true,"writeData":true}}` {
+		t.Fatal("Did not send boolean rules", headers["LifeOmic-Policy"])
+	}
+
+	WithPolicyRules(map[string]interface{}{
+		"writeData": map[string]interface{}{"attributes": []string{"project"}},
+	})(&client)
+	headers, err = client.buildHeaders(context.Background())
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if headers["LifeOmic-Policy"] != `{"rules":{"readData":true,"writeData":{"attributes":["project"]}}}` {
+		t.Fatal("Did not merge richer rules", headers["LifeOmic-Policy"])
+	}
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	mock := MockInvoker{}
+	client := LambdaClient{
+		invoker: &mock,
+		richRules: map[string]interface{}{
+			"readData": func() {},
+		},
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatal("Expected an invalid policy error", err)
+	}
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+	_, err = client.Do(req)
+	if !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatal("Expected an invalid policy error", err)
+	}
+	if mock.hasBeenCalled {
+		t.Fatal("Should not invoke the function with an invalid policy")
+	}
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+)
+
+// now returns the current time from the clock field, which tests replace
+// through withClock in export_test.go. Invoke durations and queue waits,
+// deadline headers, trace ids, the circuit breaker, JWT expiry and rate limit
+// resets all read the time through it; Watch waits through wait instead.
+func (c *LambdaClient) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return time.Now()
+}
+
+// wait blocks for d, measured by the after field when tests set it through
+// withTimer, and reports false when ctx is done first.
+func (c *LambdaClient) wait(ctx context.Context, d time.Duration) bool {
+	if c.after != nil {
+		select {
+		case <-c.after(d):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// randomBytes returns n bytes read from crypto/rand, or from the source tests
+// set through withRand. Every generated id goes through it.
+func (c *LambdaClient) randomBytes(n int) ([]byte, error) {
+	var source io.Reader = rand.Reader
+	if c.rand != nil {
+		source = c.rand
+	}
+	id := make([]byte, n)
+	_, err := io.ReadFull(source, id)
+	if err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// randomHex returns n random bytes, hex encoded.
+func (c *LambdaClient) randomHex(n int) (string, error) {
+	id, err := c.randomBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// randomUUID returns a random version 4 UUID.
+func (c *LambdaClient) randomUUID() (string, error) {
+	id, err := c.randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16]), nil
+}
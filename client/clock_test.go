@@ -0,0 +1,51 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestClockAndRand(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	now := time.Unix(1600000000, 0)
+	client := LambdaClient{
+		invoker:         &mock,
+		xrayTracing:     true,
+		checkRequestIDs: true,
+	}
+	withClock(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	})(&client)
+	withRand(bytes.NewReader(bytes.Repeat([]byte{0xab}, 64)))(&client)
+
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(time.Minute))
+	defer cancel()
+	resp, err := client.GqlWithMetadata(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	var sent Payload
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Headers[traceHeader] != "Root=1-5f5e1001-abababababababababababab" {
+		t.Fatal("Trace id should use the clock and rand", sent.Headers[traceHeader])
+	}
+	if sent.Headers[requestIDHeader] != "abababab-abab-4bab-abab-abababababab" {
+		t.Fatal("Request id should use rand", sent.Headers[requestIDHeader])
+	}
+	if sent.Headers[timeoutHeader] != "58000" {
+		t.Fatal("Timeout should use the clock", sent.Headers[timeoutHeader])
+	}
+	if resp.Duration != time.Second {
+		t.Fatal("Duration should use the clock", resp.Duration)
+	}
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Cursor reads the nodes of a Relay connection one page at a time, fetching
+// the next page only once the current one has been consumed.
+type Cursor[T any] struct {
+	ctx       context.Context
+	client    *LambdaClient
+	uri       string
+	query     string
+	variables map[string]interface{}
+	pageSize  int
+
+	nodes   []interface{}
+	after   string
+	hasNext bool
+	started bool
+}
+
+// NewCursor returns a cursor over the connection selected by query, which
+// must take $first and $after variables and select a single top-level
+// connection field with edges { node } and pageInfo { hasNextPage
+// endCursor }. Each page requests pageSize nodes along with baseVars. Nodes
+// are decoded like the results of Query, so fields selected under an alias
+// are matched by tagging them with the alias.
+func NewCursor[T any](ctx context.Context, c *LambdaClient, uri string, query string, baseVars map[string]interface{}, pageSize int) *Cursor[T] {
+	return &Cursor[T]{
+		ctx:       ctx,
+		client:    c,
+		uri:       uri,
+		query:     query,
+		variables: baseVars,
+		pageSize:  pageSize,
+	}
+}
+
+// Next returns the next node decoded into a T. It returns false once the
+// connection has no more nodes, and fetches a new page when needed. A hook
+// set WithResultHook is called with each decoded *T.
+func (cur *Cursor[T]) Next() (T, bool, error) {
+	var node T
+	for len(cur.nodes) == 0 {
+		if cur.started && !cur.hasNext {
+			return node, false, nil
+		}
+		err := cur.fetch()
+		if err != nil {
+			return node, false, err
+		}
+	}
+	err := mapstructure.Decode(cur.nodes[0], &node)
+	if err != nil {
+		return node, false, err
+	}
+	cur.nodes = cur.nodes[1:]
+	err = cur.client.runResultHook(&node)
+	if err != nil {
+		var zero T
+		return zero, false, err
+	}
+	return node, true, nil
+}
+
+func (cur *Cursor[T]) fetch() error {
+	variables, err := PageArgs{First: cur.pageSize, After: cur.after}.Merge(cur.variables)
+	if err != nil {
+		return err
+	}
+	res, err := cur.client.GqlWithContext(cur.ctx, cur.uri, cur.query, variables)
+	if err != nil {
+		return err
+	}
+	if len(*res) != 1 {
+		return fmt.Errorf("Expected a single connection field, got %d fields", len(*res))
+	}
+	var page struct {
+		Edges []struct {
+			Cursor string
+			Node   interface{}
+		}
+		PageInfo struct {
+			HasNextPage bool
+			EndCursor   string
+		}
+	}
+	for _, connection := range *res {
+		err = mapstructure.Decode(connection, &page)
+		if err != nil {
+			return err
+		}
+	}
+	cur.started = true
+	cur.nodes = make([]interface{}, len(page.Edges))
+	for i, edge := range page.Edges {
+		cur.nodes[i] = edge.Node
+	}
+	cur.after = page.PageInfo.EndCursor
+	if cur.after == "" && len(page.Edges) > 0 {
+		cur.after = page.Edges[len(page.Edges)-1].Cursor
+	}
+	cur.hasNext = page.PageInfo.HasNextPage && len(page.Edges) > 0
+	return nil
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+const MOCK_LIST_QUERY = `
+query ListApps($first: Int, $after: String) {
+	apps(first: $first, after: $after) {
+		edges { cursor node { id } }
+		pageInfo { hasNextPage endCursor }
+	}
+}
+`
+
+// pagingInvoker serves pages of total apps, pageSize at a time.
+type pagingInvoker struct {
+	total int
+	calls int
+}
+
+func (p *pagingInvoker) Invoke(ctx context.Context, input *lambda.InvokeInput, rest ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	p.calls++
+	var sent Payload
+	json.Unmarshal(input.Payload, &sent)
+	var body struct {
+		Variables struct {
+			First int
+			After string
+		}
+	}
+	json.Unmarshal([]byte(sent.Body), &body)
+	start := 0
+	if body.Variables.After != "" {
+		fmt.Sscanf(body.Variables.After, "cursor-%d", &start)
+		start++
+	}
+	edges := []interface{}{}
+	for i := start; i < start+body.Variables.First && i < p.total; i++ {
+		edges = append(edges, map[string]interface{}{
+			"cursor": fmt.Sprintf("cursor-%d", i),
+			"node":   map[string]interface{}{"id": fmt.Sprintf("app-%d", i)},
+		})
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"apps": map[string]interface{}{
+				"edges": edges,
+				"pageInfo": map[string]interface{}{
+					"hasNextPage": start+body.Variables.First < p.total,
+					"endCursor":   fmt.Sprintf("cursor-%d", start+len(edges)-1),
+				},
+			},
+		},
+	})
+	out, _ := json.Marshal(responsePayload{Body: string(data), StatusCode: 200})
+	return &lambda.InvokeOutput{Payload: out}, nil
+}
+
+func TestCursor(t *testing.T) {
+	invoker := pagingInvoker{total: 5}
+	client := &LambdaClient{
+		invoker: &invoker,
+	}
+	type app struct {
+		Id string
+	}
+
+	cursor := NewCursor[app](context.Background(), client, "some_lambda:status/some/path", MOCK_LIST_QUERY, map[string]interface{}{}, 2)
+	first, ok, err := cursor.Next()
+	if err != nil || !ok || first.Id != "app-0" {
+		t.Fatal("Did not return first node", first, ok, err)
+	}
+	if invoker.calls != 1 {
+		t.Fatal("Should only fetch the first page", invoker.calls)
+	}
+
+	ids := []string{first.Id}
+	for {
+		node, ok, err := cursor.Next()
+		if err != nil {
+			t.Fatal("Unexpected error", err)
+		}
+		if !ok {
+			break
+		}
+		ids = append(ids, node.Id)
+	}
+	if len(ids) != 5 || ids[4] != "app-4" {
+		t.Fatal("Did not return every node in order", ids)
+	}
+	if invoker.calls != 3 {
+		t.Fatal("Expected one request per page", invoker.calls)
+	}
+
+	_, ok, err = cursor.Next()
+	if ok || err != nil {
+		t.Fatal("Exhausted cursor should stay exhausted", ok, err)
+	}
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+const GET_DATASET = `
+  query GetDataset($id: ID!) {
+    dataset(id: $id) {
+      id
+      name
+      description
+    }
+  }
+`
+
+const LIST_DATASETS = `
+  query ListDatasets($first: Int, $after: String, $filter: DatasetFilter, $sort: [SortInput!]) {
+    datasets(first: $first, after: $after, filter: $filter, sort: $sort) {
+      edges {
+        node {
+          id
+          name
+          description
+        }
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+    }
+  }
+`
+
+type DataServiceClient struct {
+	graphqlUrl string
+	client     graphqlClient
+	headers    map[string]string
+}
+
+type Dataset struct {
+	Id          string
+	Name        string
+	Description string
+}
+
+// DatasetPage is one page of the datasets connection.
+type DatasetPage struct {
+	Datasets    []Dataset
+	HasNextPage bool
+	EndCursor   string
+}
+
+func (self *DataServiceClient) Gql(query string, variables map[string]interface{}) (*map[string]interface{}, error) {
+	return self.GqlWithContext(context.Background(), query, variables)
+}
+
+func (self *DataServiceClient) GqlWithContext(ctx context.Context, query string, variables map[string]interface{}) (*map[string]interface{}, error) {
+	return self.client.GqlWithContext(contextWithDefaultHeaders(ctx, self.headers), self.graphqlUrl, query, variables)
+}
+
+func (self *DataServiceClient) GetDataset(ctx context.Context, id string) (*Dataset, error) {
+	res, err := self.GqlWithContext(ctx, GET_DATASET, map[string]interface{}{"id": id})
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Dataset *Dataset
+	}
+	err = mapstructure.Decode(res, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.Dataset, nil
+}
+
+func (self *DataServiceClient) ListDatasets(ctx context.Context, opts ListOptions) (*DatasetPage, error) {
+	variables, err := opts.ToVariables()
+	if err != nil {
+		return nil, err
+	}
+	res, err := self.GqlWithContext(ctx, LIST_DATASETS, variables)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Datasets struct {
+			Edges []struct {
+				Node Dataset
+			}
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   string
+			}
+		}
+	}
+	err = mapstructure.Decode(res, &data)
+	if err != nil {
+		return nil, err
+	}
+	page := &DatasetPage{
+		Datasets:    make([]Dataset, len(data.Datasets.Edges)),
+		HasNextPage: data.Datasets.PageInfo.HasNextPage,
+		EndCursor:   data.Datasets.PageInfo.EndCursor,
+	}
+	for i, edge := range data.Datasets.Edges {
+		page.Datasets[i] = edge.Node
+	}
+	return page, nil
+}
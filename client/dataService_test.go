@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetDataset(t *testing.T) {
+	mockResponse := map[string]interface{}{
+		"dataset": map[string]interface{}{
+			"id":          "some_id",
+			"name":        "test dataset",
+			"description": "test description",
+		},
+	}
+	mockClient := MockClient{
+		response: &mockResponse,
+	}
+	client := DataServiceClient{
+		client:     &mockClient,
+		graphqlUrl: "data-service:deployed/graphql",
+	}
+	dataset, err := client.GetDataset(context.Background(), "some_id")
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if dataset == nil || dataset.Name != "test dataset" {
+		t.Fatal("Did not get back correct response", dataset)
+	}
+}
+
+func TestListDatasets(t *testing.T) {
+	mockResponse := map[string]interface{}{
+		"datasets": map[string]interface{}{
+			"edges": []interface{}{
+				map[string]interface{}{"node": map[string]interface{}{"id": "first"}},
+				map[string]interface{}{"node": map[string]interface{}{"id": "second"}},
+			},
+			"pageInfo": map[string]interface{}{"hasNextPage": true, "endCursor": "cursor"},
+		},
+	}
+	mockClient := MockClient{
+		response: &mockResponse,
+	}
+	client := DataServiceClient{
+		client:     &mockClient,
+		graphqlUrl: "data-service:deployed/graphql",
+	}
+	page, err := client.ListDatasets(context.Background(), ListOptions{Page: PageArgs{First: 2}})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if len(page.Datasets) != 2 || page.Datasets[1].Id != "second" || !page.HasNextPage || page.EndCursor != "cursor" {
+		t.Fatal("Did not get back correct page", page)
+	}
+}
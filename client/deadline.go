@@ -0,0 +1,60 @@
+package client
+
+import (
+	"strconv"
+	"time"
+)
+
+const timeoutHeader = "X-Timeout-Ms"
+
+// DeadlineFormat encodes the time left before a request's deadline as the
+// value of the deadline header. The remaining time is never negative.
+type DeadlineFormat func(remaining time.Duration) string
+
+// MillisecondsDeadline formats the remaining time as a whole number of
+// milliseconds, such as "1500". It is the format of the default X-Timeout-Ms
+// header.
+func MillisecondsDeadline(remaining time.Duration) string {
+	return strconv.FormatInt(remaining.Milliseconds(), 10)
+}
+
+// GRPCDeadline formats the remaining time like the grpc-timeout header: at
+// most 8 digits followed by a unit. It uses the finest unit whose value fits
+// in 8 digits, truncating any remainder, so 1.5 seconds is "1500000u" rather
+// than "1500m".
+func GRPCDeadline(remaining time.Duration) string {
+	units := []struct {
+		unit     string
+		duration time.Duration
+	}{
+		{"n", time.Nanosecond},
+		{"u", time.Microsecond},
+		{"m", time.Millisecond},
+		{"S", time.Second},
+		{"M", time.Minute},
+	}
+	for _, u := range units {
+		if value := remaining / u.duration; value < 100000000 {
+			return strconv.FormatInt(int64(value), 10) + u.unit
+		}
+	}
+	// Even the longest time.Duration is well under 10^8 hours.
+	return strconv.FormatInt(int64(remaining/time.Hour), 10) + "H"
+}
+
+// deadlineHeader returns the name and value of the header telling the
+// function how long it has left before deadline.
+func (c *LambdaClient) deadlineHeader(deadline time.Time) (string, string) {
+	remaining := deadline.Sub(c.now())
+	if remaining < 0 {
+		remaining = 0
+	}
+	name, format := timeoutHeader, DeadlineFormat(MillisecondsDeadline)
+	if c.deadlineHeaderName != "" {
+		name = c.deadlineHeaderName
+	}
+	if c.deadlineFormat != nil {
+		format = c.deadlineFormat
+	}
+	return name, format(remaining)
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestGRPCDeadline(t *testing.T) {
+	cases := map[time.Duration]string{
+		0:                               "0n",
+		1500 * time.Microsecond:         "1500000n",
+		1500 * time.Millisecond:         "1500000u",
+		3 * time.Minute:                 "180000m",
+		48 * time.Hour:                  "172800S",
+		100000000 * time.Minute:         "1666666H",
+		time.Duration(1<<63 - 1):        "2562047H",
+		2*time.Second + time.Nanosecond: "2000000u",
+	}
+	for remaining, expected := range cases {
+		if got := GRPCDeadline(remaining); got != expected {
+			t.Fatal("Unexpected grpc-timeout for", remaining, got, expected)
+		}
+	}
+}
+
+func TestCustomDeadlineHeader(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": "{ \"data\": { \"result\": true } }" }`),
+		},
+	}
+	now := time.Unix(1600000000, 0)
+	client := LambdaClient{
+		invoker: &mock,
+		clock:   func() time.Time { return now },
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(1500*time.Millisecond))
+	defer cancel()
+
+	_, err := client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if headers := payloadHeaders(t, &mock); headers["X-Timeout-Ms"] != "1500" {
+		t.Fatal("Expected the default deadline header", headers)
+	}
+
+	WithDeadlineHeader("grpc-timeout", GRPCDeadline)(&client)
+	_, err = client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	headers := payloadHeaders(t, &mock)
+	if headers["grpc-timeout"] != "1500000u" {
+		t.Fatal("Expected the custom deadline header", headers)
+	}
+	if _, ok := headers["X-Timeout-Ms"]; ok {
+		t.Fatal("Should not send the default deadline header", headers)
+	}
+}
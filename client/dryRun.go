@@ -0,0 +1,65 @@
+package client
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// PlannedInvocation is an invocation recorded by a DryRunRecorder instead of
+// being sent.
+type PlannedInvocation struct {
+	FunctionName   string
+	InvocationType types.InvocationType
+	// Payload is the event that would have been sent to the function, with
+	// its path, method, headers and body.
+	Payload Payload
+}
+
+// DryRunRecorder records the invocations of a client built
+// WithDryRunRecorder and answers each with a canned response. It is safe for
+// concurrent use.
+type DryRunRecorder struct {
+	response    []byte
+	mu          sync.Mutex
+	invocations []PlannedInvocation
+}
+
+// NewDryRunRecorder returns a recorder that answers every invocation with
+// the given API Gateway style response payload, such as
+// {"statusCode":200,"body":"{\"data\":{}}"}. A nil response answers with
+// an empty 200 response.
+func NewDryRunRecorder(response []byte) *DryRunRecorder {
+	if response == nil {
+		response = []byte(`{"statusCode":200,"body":""}`)
+	}
+	return &DryRunRecorder{response: response}
+}
+
+// Invocations returns the invocations recorded so far, in the order they
+// were made.
+func (r *DryRunRecorder) Invocations() []PlannedInvocation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]PlannedInvocation(nil), r.invocations...)
+}
+
+func (r *DryRunRecorder) record(functionName string, invocationType types.InvocationType, payload []byte) (*invokeOutput, error) {
+	planned := PlannedInvocation{
+		FunctionName:   functionName,
+		InvocationType: invocationType,
+	}
+	err := json.Unmarshal(payload, &planned.Payload)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.invocations = append(r.invocations, planned)
+	r.mu.Unlock()
+	status, ok := expectedInvokeStatus[invocationType]
+	if !ok {
+		status = expectedInvokeStatus[types.InvocationTypeRequestResponse]
+	}
+	return &invokeOutput{Payload: r.response, StatusCode: status}, nil
+}
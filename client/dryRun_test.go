@@ -0,0 +1,55 @@
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func TestDryRunRecorder(t *testing.T) {
+	recorder := NewDryRunRecorder([]byte(`{ "statusCode": 200, "body": "{ \"data\": { \"result\": true } }" }`))
+	client := &LambdaClient{
+		account: "test-account",
+	}
+	client = client.Clone(WithDryRunRecorder(recorder))
+
+	res, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{"id": "1"})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if !(*res)["result"].(bool) {
+		t.Fatal("Did not return the canned response", *res)
+	}
+
+	req, _ := http.NewRequest("PUT", "some-service:deployed/resource", bytes.NewBufferString("resource body"))
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatal("Unexpected response", resp, err)
+	}
+
+	invocations := recorder.Invocations()
+	if len(invocations) != 2 {
+		t.Fatal("Expected both invocations to be recorded", invocations)
+	}
+	gql, do := invocations[0], invocations[1]
+	if gql.FunctionName != "some_lambda:status" || gql.Payload.Path != "/some/path" || gql.Payload.HttpMethod != "POST" {
+		t.Fatal("Did not record the GraphQL invocation", gql)
+	}
+	if gql.Payload.Header("LifeOmic-Account") != "test-account" {
+		t.Fatal("Did not record the headers", gql.Payload.Headers)
+	}
+	if do.FunctionName != "some-service:deployed" || do.Payload.HttpMethod != "PUT" || do.Payload.Body != "resource body" {
+		t.Fatal("Did not record the Do invocation", do)
+	}
+
+	client.invocationType = types.InvocationTypeEvent
+	_, err = client.invoke(req.Context(), "some_lambda", []byte("{}"))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if recorded := recorder.Invocations(); recorded[2].InvocationType != types.InvocationTypeEvent {
+		t.Fatal("Did not record the invocation type", recorded[2])
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultDumpBodyLimit is the number of bytes of each payload written by a
+// client built WithDump, unless changed with WithDumpBodyLimit.
+const defaultDumpBodyLimit = 4096
+
+// dumper serializes the writes of clients sharing a dump writer.
+type dumper struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (c *LambdaClient) dumpLimit() int {
+	if c.dumpBodyLimit == 0 {
+		return defaultDumpBodyLimit
+	}
+	return c.dumpBodyLimit
+}
+
+func (c *LambdaClient) dumpPayload(data []byte) []byte {
+	if limit := c.dumpLimit(); limit > 0 && len(data) > limit {
+		return append(data[:limit:limit], "...(truncated)"...)
+	}
+	return data
+}
+
+func (c *LambdaClient) dumpRequest(functionName string, payload []byte) {
+	if c.dump == nil {
+		return
+	}
+	c.dump.mu.Lock()
+	defer c.dump.mu.Unlock()
+	fmt.Fprintf(c.dump.w, "--> %s\n%s\n", functionName, c.dumpPayload(payload))
+}
+
+func (c *LambdaClient) dumpResponse(functionName string, statusCode int32, duration time.Duration, payload []byte, err error) {
+	if c.dump == nil {
+		return
+	}
+	c.dump.mu.Lock()
+	defer c.dump.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(c.dump.w, "<-- %s failed after %s: %v\n", functionName, duration, err)
+		return
+	}
+	fmt.Fprintf(c.dump.w, "<-- %s %d (%s)\n%s\n", functionName, statusCode, duration, c.dumpPayload(payload))
+}
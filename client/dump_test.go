@@ -0,0 +1,51 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestDump(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			StatusCode: 200,
+			Payload:    []byte(`{ "statusCode": 200, "body": "{ \"data\": { \"value\": \"` + body + `\" } }" }`),
+		},
+	}
+	var dump bytes.Buffer
+	client := (&LambdaClient{invoker: &mock}).Clone(WithDump(&dump), WithDumpBodyLimit(20))
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, nil)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	lines := strings.Split(strings.TrimSpace(dump.String()), "\n")
+	if len(lines) != 4 || lines[0] != "--> some_lambda:status" || !strings.HasPrefix(lines[2], "<-- some_lambda:status 200") {
+		t.Fatal("Expected the request and response to be dumped", dump.String())
+	}
+	if lines[1] != string(mock.payload.Payload[:20])+"...(truncated)" {
+		t.Fatal("Expected the request payload to be truncated", lines[1])
+	}
+	if lines[3] != string(mock.response.Payload[:20])+"...(truncated)" {
+		t.Fatal("Expected the response payload to be truncated", lines[3])
+	}
+
+	dump.Reset()
+	client = client.Clone(WithDumpBodyLimit(0))
+	_, _ = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, nil)
+	if !strings.Contains(dump.String(), body) || strings.Contains(dump.String(), "...(truncated)") {
+		t.Fatal("Expected payloads to be dumped in full", dump.String())
+	}
+}
+
+func TestDumpDefaultLimit(t *testing.T) {
+	client := LambdaClient{}
+	dumped := client.dumpPayload(bytes.Repeat([]byte("a"), defaultDumpBodyLimit+1))
+	if len(dumped) != defaultDumpBodyLimit+len("...(truncated)") {
+		t.Fatal("Expected payloads to be truncated at 4KB by default", len(dumped))
+	}
+}
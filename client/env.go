@@ -0,0 +1,37 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	accountEnv     = "LIFEOMIC_ACCOUNT"
+	userEnv        = "LIFEOMIC_USER"
+	policyRulesEnv = "LIFEOMIC_POLICY_RULES"
+)
+
+// BuildClientFromEnv is like BuildClient but reads the account and user from
+// the LIFEOMIC_ACCOUNT and LIFEOMIC_USER environment variables, and the
+// rules from LIFEOMIC_POLICY_RULES, a JSON object of rule names to booleans
+// such as {"readData":true}. The account and user are required; without
+// rules the policy is sent without any.
+func BuildClientFromEnv(opts ...Option) (*LambdaClient, error) {
+	account, ok := os.LookupEnv(accountEnv)
+	if !ok || account == "" {
+		return nil, fmt.Errorf("%w: %s", ErrMissingEnvironment, accountEnv)
+	}
+	user, ok := os.LookupEnv(userEnv)
+	if !ok || user == "" {
+		return nil, fmt.Errorf("%w: %s", ErrMissingEnvironment, userEnv)
+	}
+	var rules map[string]bool
+	if encoded := os.Getenv(policyRulesEnv); encoded != "" {
+		err := json.Unmarshal([]byte(encoded), &rules)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s: %w", policyRulesEnv, err)
+		}
+	}
+	return BuildClient(account, user, rules, opts...)
+}
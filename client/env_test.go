@@ -0,0 +1,32 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuildClientFromEnv(t *testing.T) {
+	t.Setenv("LIFEOMIC_ACCOUNT", "")
+	t.Setenv("LIFEOMIC_USER", "test-user")
+	_, err := BuildClientFromEnv()
+	if !errors.Is(err, ErrMissingEnvironment) || !strings.Contains(err.Error(), "LIFEOMIC_ACCOUNT") {
+		t.Fatal("Expected a missing account error", err)
+	}
+
+	t.Setenv("LIFEOMIC_ACCOUNT", "test-account")
+	t.Setenv("LIFEOMIC_POLICY_RULES", "{not json")
+	_, err = BuildClientFromEnv()
+	if err == nil || !strings.Contains(err.Error(), "LIFEOMIC_POLICY_RULES") {
+		t.Fatal("Expected an invalid rules error", err)
+	}
+
+	t.Setenv("LIFEOMIC_POLICY_RULES", `{ "readData": true }`)
+	client, err := BuildClientFromEnv(WithUser("other-user"))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if client.account != "test-account" || client.user != "other-user" || !client.rules["readData"] {
+		t.Fatal("Did not build the client from the environment", client.account, client.user, client.rules)
+	}
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// ErrDecodeEnvelope is returned when the Lambda response payload can not be
+// decoded into the API Gateway style envelope.
+var ErrDecodeEnvelope = errors.New("Failed to decode lambda response envelope")
+
+// ErrDecodeBody is returned when the body of the Lambda response can not be
+// decoded as a GraphQL response.
+var ErrDecodeBody = errors.New("Failed to decode response body")
+
+// ErrClientContextTooLarge is returned when the encoded client context exceeds
+// the 3583 byte limit Lambda places on it.
+var ErrClientContextTooLarge = errors.New("Client context is too large")
+
+// ErrClientClosed is returned by requests made after the client was closed.
+var ErrClientClosed = errors.New("Client is closed")
+
+// ErrSubscriptionsUnsupported is returned by Subscribe, since subscriptions
+// can not be served over Lambda invocations.
+var ErrSubscriptionsUnsupported = errors.New("GraphQL subscriptions are not supported over lambda invocations")
+
+// ErrFunctionError is returned when the invoked function failed with an
+// unhandled error instead of producing a response.
+var ErrFunctionError = errors.New("Lambda function returned an error")
+
+// ErrQueryTooComplex is returned, without invoking the function, for queries
+// nested deeper than the limit set with WithMaxQueryDepth.
+var ErrQueryTooComplex = errors.New("Query is too complex")
+
+// ErrUnexpectedInvokeStatus is returned when the Invoke API answers with a
+// status code that does not match the invocation type used.
+var ErrUnexpectedInvokeStatus = errors.New("Unexpected invoke status code")
+
+// ErrInvalidUTF8Body is returned by Do for request bodies that are not valid
+// UTF-8 and so can not be sent as a JSON string. Binary bodies can be sent
+// by building the client WithBase64Bodies.
+var ErrInvalidUTF8Body = errors.New("Request body is not valid UTF-8, use WithBase64Bodies to send binary bodies")
+
+// ErrCircuitOpen is returned, without invoking the function, while the
+// circuit breaker set with WithCircuitBreaker is open for the function.
+var ErrCircuitOpen = errors.New("Circuit breaker is open")
+
+// ErrInvalidPolicy is returned, without invoking the function, when the policy
+// rules can not be encoded as JSON for the LifeOmic-Policy header.
+var ErrInvalidPolicy = errors.New("Failed to encode policy")
+
+// ErrInvalidJWT is returned by requests made with a token given to WithJWT
+// that can not be decoded.
+var ErrInvalidJWT = errors.New("Invalid JWT")
+
+// ErrJWTExpired is returned, without invoking the function, by requests made
+// after the token given to WithJWT has expired.
+var ErrJWTExpired = errors.New("JWT has expired")
+
+// ErrRequestTimeout is matched by the errors of requests that ran out of the
+// time given by WithRequestTimeout. Those errors also match
+// context.DeadlineExceeded.
+var ErrRequestTimeout = errors.New("Request timed out")
+
+// ErrMissingEnvironment is returned by BuildClientFromEnv when a required
+// environment variable is not set.
+var ErrMissingEnvironment = errors.New("Missing required environment variable")
+
+// ErrInvalidPayloadPointer is returned when a function answers with a
+// large-payload pointer outside the bucket set with WithLargePayloadBucket.
+var ErrInvalidPayloadPointer = errors.New("Invalid large payload pointer")
+
+// ErrMissingObjectStore is returned by requests of a client with a
+// large-payload bucket but no ObjectStore to reach it, such as one built
+// without BuildClient.
+var ErrMissingObjectStore = errors.New("Large payload bucket is set without an object store")
+
+// ErrFunctionNotFound is returned by CheckFunction when the function does
+// not exist.
+var ErrFunctionNotFound = errors.New("Lambda function not found")
+
+// ErrAccessDenied is returned by CheckFunction when the client's AWS
+// credentials are not allowed to invoke the function.
+var ErrAccessDenied = errors.New("Not allowed to invoke lambda function")
+
+// ErrMissingAccount is returned by requests that address an account in their
+// path, such as those of the FHIR client, when the client has no account.
+var ErrMissingAccount = errors.New("Missing account")
+
+// requestTimeoutError is returned for requests that ran out of the time given
+// by WithRequestTimeout.
+type requestTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e *requestTimeoutError) Error() string {
+	return fmt.Sprintf("%v after %s", ErrRequestTimeout, e.timeout)
+}
+
+func (e *requestTimeoutError) Is(target error) bool {
+	return target == ErrRequestTimeout || target == context.DeadlineExceeded
+}
+
+// timeoutError replaces a deadline error with a requestTimeoutError when the
+// deadline was the client's request timeout rather than that of the caller's
+// context parent.
+func (c *LambdaClient) timeoutError(parent context.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) && parent.Err() == nil {
+		return &requestTimeoutError{timeout: c.requestTimeout}
+	}
+	return err
+}
+
+const maxErrorSnippet = 256
+
+// snippet returns data, redacted and truncated, for inclusion in an error
+// message.
+func snippet(data []byte) string {
+	data = redact(data)
+	if len(data) > maxErrorSnippet {
+		return string(data[:maxErrorSnippet]) + "...(truncated)"
+	}
+	return string(data)
+}
+
+// DecodeError is returned when a response can not be decoded. It matches
+// ErrDecodeEnvelope or ErrDecodeBody, depending on what failed to decode.
+type DecodeError struct {
+	kind error
+	err  error
+	// RawPayload holds the data that failed to decode, with the values of
+	// fields that look like credentials replaced by "[REDACTED]".
+	RawPayload []byte
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%v: %v: %q", e.kind, e.err, snippet(e.RawPayload))
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.kind
+}
+
+var sensitiveFields = regexp.MustCompile(`(?i)("[^"]*(?:authorization|cookie|password|passwd|secret|token|api[-_]?key|credential|session)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+var jsonStrings = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+// redact replaces the string values of JSON fields named like credentials,
+// including those of JSON documents nested in strings, such as the body of
+// a response payload.
+func redact(data []byte) []byte {
+	data = sensitiveFields.ReplaceAll(data, []byte(`$1"[REDACTED]"`))
+	return jsonStrings.ReplaceAllFunc(data, redactNested)
+}
+
+// redactNested redacts a JSON string literal holding escaped JSON.
+func redactNested(literal []byte) []byte {
+	if !bytes.Contains(literal, []byte(`\"`)) {
+		return literal
+	}
+	var inner string
+	if json.Unmarshal(literal, &inner) != nil {
+		return literal
+	}
+	redacted := redact([]byte(inner))
+	if string(redacted) == inner {
+		return literal
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if encoder.Encode(string(redacted)) != nil {
+		return literal
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func decodeError(kind error, err error, data []byte) error {
+	return &DecodeError{kind: kind, err: err, RawPayload: redact(data)}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestGqlDecodeErrors(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("not json"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrDecodeEnvelope) {
+		t.Fatal("Expected an envelope decode error", err)
+	}
+	if !strings.Contains(err.Error(), "not json") {
+		t.Fatal("Error should include the offending payload", err)
+	}
+
+	mock.response = &lambda.InvokeOutput{
+		Payload: []byte("{ \"body\": \"<html>\" }"),
+	}
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrDecodeBody) {
+		t.Fatal("Expected a body decode error", err)
+	}
+	if !strings.Contains(err.Error(), "<html>") {
+		t.Fatal("Error should include the offending body", err)
+	}
+}
+
+func TestSnippetTruncates(t *testing.T) {
+	s := snippet([]byte(strings.Repeat("a", maxErrorSnippet+1)))
+	if !strings.HasSuffix(s, "...(truncated)") {
+		t.Fatal("Expected truncation marker", s)
+	}
+}
+
+func TestDecodeErrorRawPayload(t *testing.T) {
+	payload := `{ "statusCode": 200, "headers": { "Authorization": "Bearer abc" }, "body": 42, "password": "hunter2" `
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(payload),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatal("Expected a DecodeError", err)
+	}
+	expected := `{ "statusCode": 200, "headers": { "Authorization": "[REDACTED]" }, "body": 42, "password": "[REDACTED]" `
+	if string(decodeErr.RawPayload) != expected {
+		t.Fatal("Expected the redacted raw payload", string(decodeErr.RawPayload))
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Fatal("Error should not include redacted values", err)
+	}
+}
+
+func TestDecodeErrorRedactsEscapedBody(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "statusCode": 200, "headers": { "Cookie": "session=abc", "X-Api-Key": "key" }, "body": "{\"password\":\"hunter2\",\"nested\":\"{\\\"token\\\":\\\"t0k\\\"}\"}" `),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	var decodeErr *DecodeError
+	if !errors.Is(err, ErrDecodeEnvelope) || !errors.As(err, &decodeErr) {
+		t.Fatal("Expected an envelope DecodeError", err)
+	}
+	for _, secret := range []string{"hunter2", "t0k", "session=abc", `"key"`} {
+		if strings.Contains(string(decodeErr.RawPayload), secret) || strings.Contains(err.Error(), secret) {
+			t.Fatal("Expected the escaped body and headers to be redacted", secret, string(decodeErr.RawPayload))
+		}
+	}
+	if !strings.Contains(string(decodeErr.RawPayload), `\"password\":\"[REDACTED]\"`) {
+		t.Fatal("Expected the escaped field to be kept", string(decodeErr.RawPayload))
+	}
+}
+
+func TestFunctionErrorRedacted(t *testing.T) {
+	functionError := "Unhandled"
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			FunctionError: &functionError,
+			Payload:       []byte(`{ "errorMessage": "boom", "event": { "headers": { "Authorization": "Bearer abc" } } }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrFunctionError) {
+		t.Fatal("Expected a function error", err)
+	}
+	if strings.Contains(err.Error(), "Bearer abc") || !strings.Contains(err.Error(), "boom") {
+		t.Fatal("Expected the function error payload to be redacted", err)
+	}
+}
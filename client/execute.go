@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// GraphQLRequest is a GraphQL request as sent in the body of the event.
+type GraphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+	// OperationName selects the operation to run when Query holds several.
+	OperationName string `json:"operationName,omitempty"`
+	// Extensions is sent as is, for protocol extensions such as persisted
+	// query hashes.
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLResponse is a GraphQL response with its data left undecoded.
+type GraphQLResponse struct {
+	Data       json.RawMessage        `json:"data"`
+	Errors     []GraphQLError         `json:"errors"`
+	Extensions map[string]interface{} `json:"extensions"`
+	// Accepted reports that Lambda queued the request of a client using
+	// Event invocations, so there is no response yet.
+	Accepted bool `json:"-"`
+}
+
+// Execute sends a GraphQL request with every field under the caller's
+// control and returns the response as received, for protocols the other
+// methods do not cover. Like GqlRawData, the returned error is only set when
+// the request itself fails; GraphQL errors are returned in the response,
+// truncated when the client was built WithMaxReportedErrors.
+func (c *LambdaClient) Execute(ctx context.Context, uri string, req GraphQLRequest) (GraphQLResponse, error) {
+	prepared, err := c.Prepare(uri, req.Query)
+	if err != nil {
+		return GraphQLResponse{}, err
+	}
+	prepared.operationName = req.OperationName
+	prepared.extensions = req.Extensions
+	raw, invoked, err := c.gqlBody(ctx, prepared, req.Variables)
+	if err != nil {
+		return GraphQLResponse{}, err
+	}
+	if eventAccepted(invoked) {
+		return GraphQLResponse{Accepted: true}, nil
+	}
+	var resp GraphQLResponse
+	err = json.Unmarshal(raw, &resp)
+	if err != nil {
+		return GraphQLResponse{}, decodeError(ErrDecodeBody, err, raw)
+	}
+	resp.Errors, _ = c.limitErrors(resp.Errors)
+	return resp, nil
+}
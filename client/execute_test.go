@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestExecute(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": { "data": { "app": { "id": "1" } }, "errors": [{ "message": "deprecated", "extensions": { "code": "DEPRECATED" } }], "extensions": { "cost": 3 } } }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	resp, err := client.Execute(context.Background(), "some_lambda:status/some/path", GraphQLRequest{
+		Query:         "query First { app { id } } query Second { other }",
+		Variables:     map[string]interface{}{"id": "1"},
+		OperationName: "First",
+		Extensions:    map[string]interface{}{"persistedQuery": map[string]interface{}{"version": 1}},
+	})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	sent, _ := DecodePayload(mock.payload.Payload)
+	var body GraphQLRequest
+	err = json.Unmarshal([]byte(sent.Body), &body)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if body.Query != "query First { app { id } } query Second { other }" || body.Variables["id"] != "1" {
+		t.Fatal("Did not send the query and variables", body)
+	}
+	if body.OperationName != "First" {
+		t.Fatal("Did not send the operation name", body)
+	}
+	if persisted, ok := body.Extensions["persistedQuery"].(map[string]interface{}); !ok || persisted["version"] != float64(1) {
+		t.Fatal("Did not send the extensions", body.Extensions)
+	}
+
+	if string(resp.Data) != `{ "app": { "id": "1" } }` {
+		t.Fatal("Did not return the raw data", string(resp.Data))
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Code() != "DEPRECATED" {
+		t.Fatal("Did not return the errors", resp.Errors)
+	}
+	if resp.Extensions["cost"] != float64(3) {
+		t.Fatal("Did not return the extensions", resp.Extensions)
+	}
+
+	_, err = client.Execute(context.Background(), "some_lambda:status/some/path", GraphQLRequest{Query: MOCK_MUTATION})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent, _ = DecodePayload(mock.payload.Payload)
+	var fields map[string]interface{}
+	_ = json.Unmarshal([]byte(sent.Body), &fields)
+	if _, ok := fields["operationName"]; ok {
+		t.Fatal("Expected an empty operation name to be omitted", fields)
+	}
+	if _, ok := fields["extensions"]; ok {
+		t.Fatal("Expected empty extensions to be omitted", fields)
+	}
+}
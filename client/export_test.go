@@ -0,0 +1,40 @@
+package client
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// withClock replaces the clock the client reads the time from.
+func withClock(clock func() time.Time) Option {
+	return func(c *LambdaClient) {
+		c.clock = clock
+	}
+}
+
+// withRand replaces crypto/rand as the source of generated ids. Reads are
+// serialized so concurrent requests can share the source.
+func withRand(source io.Reader) Option {
+	return func(c *LambdaClient) {
+		c.rand = &lockedReader{r: source}
+	}
+}
+
+// withTimer replaces the timer Watch waits between polls with.
+func withTimer(after func(time.Duration) <-chan time.Time) Option {
+	return func(c *LambdaClient) {
+		c.after = after
+	}
+}
+
+type lockedReader struct {
+	mu sync.Mutex
+	r  io.Reader
+}
+
+func (l *lockedReader) Read(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Read(p)
+}
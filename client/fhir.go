@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type httpDoer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// FHIRClient reads resources from the PHC FHIR service.
+type FHIRClient struct {
+	baseUrl string
+	account string
+	client  httpDoer
+	headers map[string]string
+}
+
+// FHIRError is returned when the FHIR service answers with an error status.
+type FHIRError struct {
+	StatusCode int
+	// Body is the response body, usually a FHIR OperationOutcome.
+	Body []byte
+}
+
+func (e *FHIRError) Error() string {
+	return fmt.Sprintf("FHIR request failed with status %d: %q", e.StatusCode, snippet(e.Body))
+}
+
+// ReadResource returns the resource of the given type and id, such as
+// ("Patient", "some_id").
+func (self *FHIRClient) ReadResource(ctx context.Context, resourceType string, id string) (map[string]interface{}, error) {
+	resourceUrl, err := self.resourceUrl(resourceType, id)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", resourceUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	return self.do(req)
+}
+
+// SearchResources searches resources of the given type and returns the
+// resulting Bundle. params are FHIR search parameters such as
+// {"subject": {"Patient/some_id"}, "_count": {"10"}}. The search is sent as
+// a POST to _search so parameters are not limited by URL length.
+func (self *FHIRClient) SearchResources(ctx context.Context, resourceType string, params url.Values) (map[string]interface{}, error) {
+	searchUrl, err := self.resourceUrl(resourceType, "_search")
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", searchUrl, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return self.do(req)
+}
+
+// resourceUrl returns the URL of the given path segments under the
+// client's account, escaping each segment. It returns ErrMissingAccount when
+// the client has no account, rather than addressing the service root.
+func (self *FHIRClient) resourceUrl(segments ...string) (string, error) {
+	if self.account == "" {
+		return "", ErrMissingAccount
+	}
+	resourceUrl := self.baseUrl + "/" + url.PathEscape(self.account) + "/dstu3"
+	for _, segment := range segments {
+		resourceUrl += "/" + url.PathEscape(segment)
+	}
+	return resourceUrl, nil
+}
+
+func (self *FHIRClient) do(req *http.Request) (map[string]interface{}, error) {
+	req = req.WithContext(contextWithDefaultHeaders(req.Context(), self.headers))
+	req.Header.Set("Accept", "application/fhir+json")
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &FHIRError{StatusCode: resp.StatusCode, Body: body}
+	}
+	var resource map[string]interface{}
+	err = json.Unmarshal(body, &resource)
+	if err != nil {
+		return nil, decodeError(ErrDecodeBody, err, body)
+	}
+	return resource, nil
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestFHIR(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "statusCode": 200, "body": "{ \"resourceType\": \"Patient\", \"id\": \"some_id\" }" }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+		account: "some_account",
+	}
+	fhir := client.FHIR(WithDefaultHeaders(map[string]string{"X-Service": "fhir"}))
+
+	patient, err := fhir.ReadResource(context.Background(), "Patient", "some_id")
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if patient["id"] != "some_id" {
+		t.Fatal("Did not return resource", patient)
+	}
+	var sent Payload
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if *mock.payload.FunctionName != "fhir-service:deployed" || sent.Path != "/some_account/dstu3/Patient/some_id" || sent.HttpMethod != "GET" {
+		t.Fatal("Did not read from the FHIR service", *mock.payload.FunctionName, sent.HttpMethod, sent.Path)
+	}
+	if sent.Headers["X-Service"] != "fhir" {
+		t.Fatal("Did not send default headers", sent.Headers)
+	}
+
+	_, err = fhir.SearchResources(context.Background(), "Observation", url.Values{"subject": {"Patient/some_id"}})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent = Payload{}
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Path != "/some_account/dstu3/Observation/_search" || sent.HttpMethod != "POST" || sent.Body != "subject=Patient%2Fsome_id" {
+		t.Fatal("Did not search the FHIR service", sent.HttpMethod, sent.Path, sent.Body)
+	}
+
+	mock.response = &lambda.InvokeOutput{
+		Payload: []byte(`{ "statusCode": 404, "body": "{ \"resourceType\": \"OperationOutcome\" }" }`),
+	}
+	_, err = fhir.ReadResource(context.Background(), "Patient", "missing")
+	var fhirErr *FHIRError
+	if !errors.As(err, &fhirErr) || fhirErr.StatusCode != 404 {
+		t.Fatal("Expected a FHIR error", err)
+	}
+
+	_, _ = fhir.ReadResource(context.Background(), "Patient/../Group", "a/b?c")
+	sent = Payload{}
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Path != "/some_account/dstu3/Patient%2F..%2FGroup/a%2Fb%3Fc" {
+		t.Fatal("Did not escape the resource type and id", sent.Path)
+	}
+
+	mock.hasBeenCalled = false
+	client.account = ""
+	fhir = client.FHIR()
+	_, err = fhir.ReadResource(context.Background(), "Patient", "some_id")
+	if !errors.Is(err, ErrMissingAccount) || mock.hasBeenCalled {
+		t.Fatal("Expected a missing account error", err)
+	}
+	_, err = fhir.SearchResources(context.Background(), "Patient", nil)
+	if !errors.Is(err, ErrMissingAccount) || mock.hasBeenCalled {
+		t.Fatal("Expected a missing account error", err)
+	}
+}
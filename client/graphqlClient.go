@@ -1,5 +1,7 @@
 package client
 
+import "context"
+
 type graphqlClient interface {
-	Gql(string, string, map[string]interface{}) (*map[string]interface{}, error)
+	GqlWithContext(context.Context, string, string, map[string]interface{}) (*map[string]interface{}, error)
 }
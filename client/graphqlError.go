@@ -0,0 +1,140 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// GraphQLError is an entry of the errors list of a GraphQL response.
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e GraphQLError) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is a GraphQLError with the same message and
+// extensions.code, so errors.Is can find an error inside GraphQLErrors even
+// though the map and slice fields keep GraphQLError from being compared
+// with ==.
+func (e GraphQLError) Is(target error) bool {
+	var other GraphQLError
+	switch t := target.(type) {
+	case GraphQLError:
+		other = t
+	case *GraphQLError:
+		if t == nil {
+			return false
+		}
+		other = *t
+	default:
+		return false
+	}
+	return e.Message == other.Message && e.Code() == other.Code()
+}
+
+// Code returns the extensions.code of the error, or "" when it has none.
+func (e GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// GraphQLErrors is returned for responses with more than one GraphQL error.
+// Its message is that of the first error followed by the number of others,
+// and errors.Is and errors.As match against each of the errors in turn.
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	switch len(e) {
+	case 0:
+		return "No GraphQL errors"
+	case 1:
+		return e[0].Message
+	}
+	return fmt.Sprintf("%s (and %d more)", e[0].Message, len(e)-1)
+}
+
+// ByCode returns the first error with the given extensions.code.
+func (e GraphQLErrors) ByCode(code string) (GraphQLError, bool) {
+	for _, err := range e {
+		if err.Code() == code {
+			return err, true
+		}
+	}
+	return GraphQLError{}, false
+}
+
+func (e GraphQLErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Is and As let errors.Is and errors.As look into each error on Go versions
+// that do not follow Unwrap() []error.
+func (e GraphQLErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e GraphQLErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinGraphQLErrors returns the only error of errs as is, and GraphQLErrors
+// for more.
+func joinGraphQLErrors(errs []GraphQLError) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return GraphQLErrors(errs)
+}
+
+// limitErrors truncates errs to the client's maximum number of reported
+// errors and returns how many were dropped.
+func (c *LambdaClient) limitErrors(errs []GraphQLError) ([]GraphQLError, int) {
+	if c.maxReportedErrors <= 0 || len(errs) <= c.maxReportedErrors {
+		return errs, 0
+	}
+	return errs[:c.maxReportedErrors], len(errs) - c.maxReportedErrors
+}
+
+var statusForCode = map[string]int{
+	"BAD_USER_INPUT":            http.StatusBadRequest,
+	"GRAPHQL_PARSE_FAILED":      http.StatusBadRequest,
+	"GRAPHQL_VALIDATION_FAILED": http.StatusBadRequest,
+	"UNAUTHENTICATED":           http.StatusUnauthorized,
+	"FORBIDDEN":                 http.StatusForbidden,
+	"NOT_FOUND":                 http.StatusNotFound,
+}
+
+// HTTPStatusForError maps the extensions.code of a GraphQLError returned by
+// the client to an HTTP status code. Errors without a known code map to 500,
+// and a nil error maps to 200.
+func HTTPStatusForError(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var gqlErr GraphQLError
+	if errors.As(err, &gqlErr) {
+		if status, ok := statusForCode[gqlErr.Code()]; ok {
+			return status
+		}
+	}
+	return http.StatusInternalServerError
+}
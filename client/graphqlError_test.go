@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestGqlReturnsGraphQLError(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{\\\"errors\\\": [{ \\\"message\\\": \\\"not allowed\\\", \\\"extensions\\\": { \\\"code\\\": \\\"FORBIDDEN\\\" } }] }\" }"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	var gqlErr GraphQLError
+	if !errors.As(err, &gqlErr) {
+		t.Fatal("Expected a GraphQLError", err)
+	}
+	if gqlErr.Code() != "FORBIDDEN" {
+		t.Fatal("Did not decode extension code", gqlErr)
+	}
+	if HTTPStatusForError(err) != http.StatusForbidden {
+		t.Fatal("Expected 403", HTTPStatusForError(err))
+	}
+}
+
+func TestHTTPStatusForError(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected int
+	}{
+		{nil, http.StatusOK},
+		{GraphQLError{Message: "a", Extensions: map[string]interface{}{"code": "UNAUTHENTICATED"}}, http.StatusUnauthorized},
+		{GraphQLError{Message: "b", Extensions: map[string]interface{}{"code": "FORBIDDEN"}}, http.StatusForbidden},
+		{GraphQLError{Message: "c", Extensions: map[string]interface{}{"code": "NOT_FOUND"}}, http.StatusNotFound},
+		{GraphQLError{Message: "d", Extensions: map[string]interface{}{"code": "SOMETHING_ELSE"}}, http.StatusInternalServerError},
+		{GraphQLError{Message: "e"}, http.StatusInternalServerError},
+		{errors.New("invoke failed"), http.StatusInternalServerError},
+		{fmt.Errorf("wrapped: %w", GraphQLError{Message: "f", Extensions: map[string]interface{}{"code": "NOT_FOUND"}}), http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if status := HTTPStatusForError(c.err); status != c.expected {
+			t.Fatalf("Expected %d for %v, got %d", c.expected, c.err, status)
+		}
+	}
+}
+
+func TestPartialErrorHandler(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"app\\\": null }, \\\"errors\\\": [{ \\\"message\\\": \\\"partial\\\", \\\"path\\\": [\\\"app\\\"] }] }\" }"),
+		},
+	}
+	var handled []GraphQLError
+	client := LambdaClient{
+		invoker: &mock,
+		partialErrorHandler: func(errs []GraphQLError) {
+			handled = errs
+		},
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if len(handled) != 1 || handled[0].Message != "partial" || handled[0].Path[0] != "app" {
+		t.Fatal("Handler was not called with errors", handled)
+	}
+
+	handled = nil
+	mock.response = &lambda.InvokeOutput{
+		Payload: []byte("{ \"body\": \"{ \\\"errors\\\": [{ \\\"message\\\": \\\"failed\\\" }] }\" }"),
+	}
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if handled != nil {
+		t.Fatal("Handler should only be called for partial data", handled)
+	}
+}
+
+func TestStrictErrors(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"app\\\": null, \\\"name\\\": \\\"test\\\" }, \\\"errors\\\": [{ \\\"message\\\": \\\"partial\\\", \\\"path\\\": [\\\"app\\\"] }] }\" }"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	res, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || err.Error() != "partial" {
+		t.Fatal("Expected the GraphQL error", err)
+	}
+	if res == nil || (*res)["name"] != "test" {
+		t.Fatal("Expected partial data along with the error", res)
+	}
+
+	client.strictErrors = true
+	res, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || err.Error() != "partial" {
+		t.Fatal("Expected the GraphQL error", err)
+	}
+	if res != nil {
+		t.Fatal("Strict errors should not return data", *res)
+	}
+}
+
+func TestMaxReportedErrors(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": { "data": { "app": null }, "errors": [{ "message": "first" }, { "message": "second" }, { "message": "third" }] } }`),
+		},
+	}
+	var handled []GraphQLError
+	client := LambdaClient{
+		invoker: &mock,
+		partialErrorHandler: func(errs []GraphQLError) {
+			handled = errs
+		},
+	}
+
+	res, err := client.GqlWithMetadata(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || err.Error() != "first (and 2 more)" || len(handled) != 3 || len(res.Errors) != 3 || res.OmittedErrors != 0 {
+		t.Fatal("Should report every error by default", err, handled, res)
+	}
+
+	WithMaxReportedErrors(1)(&client)
+	res, err = client.GqlWithMetadata(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || err.Error() != "first (2 of 3 errors omitted)" {
+		t.Fatal("Expected the error to note the omitted errors", err)
+	}
+	var gqlErr GraphQLError
+	if !errors.As(err, &gqlErr) || gqlErr.Message != "first" {
+		t.Fatal("Expected the first GraphQLError to be wrapped", err)
+	}
+	if len(handled) != 1 || len(res.Errors) != 1 || res.OmittedErrors != 2 {
+		t.Fatal("Expected errors to be truncated", handled, res)
+	}
+
+	_, errs, err := client.GqlRawData(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil || len(errs) != 1 {
+		t.Fatal("Expected raw errors to be truncated", errs, err)
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": { "errors": [{ "message": "bad input", "extensions": { "code": "BAD_USER_INPUT" } }, { "message": "not allowed", "extensions": { "code": "FORBIDDEN" } }] } }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	var gqlErrs GraphQLErrors
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) != 2 || err.Error() != "bad input (and 1 more)" {
+		t.Fatal("Expected GraphQLErrors", err)
+	}
+	if len(gqlErrs.Unwrap()) != 2 {
+		t.Fatal("Expected every error to be unwrapped", gqlErrs.Unwrap())
+	}
+	forbidden, ok := gqlErrs.ByCode("FORBIDDEN")
+	if !ok || forbidden.Message != "not allowed" {
+		t.Fatal("Expected to extract the FORBIDDEN error", gqlErrs)
+	}
+	if _, ok := gqlErrs.ByCode("NOT_FOUND"); ok {
+		t.Fatal("Expected no NOT_FOUND error", gqlErrs)
+	}
+	var first GraphQLError
+	if !errors.As(err, &first) || first.Code() != "BAD_USER_INPUT" {
+		t.Fatal("Expected errors.As to match the first error", first)
+	}
+	if !errors.Is(err, gqlErrs[1]) || !errors.Is(gqlErrs, gqlErrs[0]) {
+		t.Fatal("Expected errors.Is to match each of the errors", err)
+	}
+	notAllowed := GraphQLError{Message: "not allowed", Extensions: map[string]interface{}{"code": "FORBIDDEN"}}
+	if !errors.Is(err, &notAllowed) {
+		t.Fatal("Expected errors.Is to match on message and code", err)
+	}
+	if errors.Is(err, GraphQLError{Message: "not allowed"}) {
+		t.Fatal("Expected errors.Is to compare codes too", err)
+	}
+	if GraphQLErrors(nil).Error() == "" {
+		t.Fatal("Expected a message for empty errors")
+	}
+}
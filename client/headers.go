@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type headersKey struct{}
+
+// ContextWithHeaders returns a context that adds the given headers to every
+// request made with it. When ctx already carries headers the two sets are
+// merged, with the new headers taking precedence.
+//
+// Headers are applied in this order, later ones overriding earlier ones:
+// the client's base headers (identity, policy and content type), the default
+// headers of the sub-client making the call, headers from the context, and
+// for Do the headers of the request. The identity headers LifeOmic-Account,
+// LifeOmic-User and LifeOmic-Policy, or the names set with WithHeaderNames,
+// can not be overridden.
+func ContextWithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	existing := headersFromContext(ctx)
+	merged := make(map[string]string, len(existing)+len(headers))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range headers {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+func headersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
+}
+
+// contextWithDefaultHeaders adds headers to ctx beneath any headers it
+// already carries.
+func contextWithDefaultHeaders(ctx context.Context, defaults map[string]string) context.Context {
+	if len(defaults) == 0 {
+		return ctx
+	}
+	return ContextWithHeaders(ContextWithHeaders(ctx, defaults), headersFromContext(ctx))
+}
+
+// HeaderNames are the names of the headers the client sends its identity
+// in. Empty names keep their default.
+type HeaderNames struct {
+	// Account defaults to LifeOmic-Account.
+	Account string
+	// User defaults to LifeOmic-User.
+	User string
+	// Policy defaults to LifeOmic-Policy.
+	Policy string
+}
+
+// defaultHeaderNames are the identity header names used unless
+// WithHeaderNames sets others.
+var defaultHeaderNames = HeaderNames{
+	Account: "LifeOmic-Account",
+	User:    "LifeOmic-User",
+	Policy:  "LifeOmic-Policy",
+}
+
+// identityHeaders returns the identity header names of the client, with
+// defaults filled in.
+func (c *LambdaClient) identityHeaders() HeaderNames {
+	names := defaultHeaderNames
+	if c.headerNames.Account != "" {
+		names.Account = c.headerNames.Account
+	}
+	if c.headerNames.User != "" {
+		names.User = c.headerNames.User
+	}
+	if c.headerNames.Policy != "" {
+		names.Policy = c.headerNames.Policy
+	}
+	return names
+}
+
+// mergeHeaders adds the per-request headers extra to base. Names are compared
+// case-insensitively, so a per-request header replaces any base header of
+// the same name instead of being sent alongside it, except for the identity
+// headers and, when the client sets it from WithJWT or WithTokenProvider,
+// the Authorization header, which are left as they are so the credentials
+// forwarded always match the identity sent with them.
+func (c *LambdaClient) mergeHeaders(base map[string]string, extra map[string]string) {
+	identity := c.identityHeaders()
+	protected := map[string]bool{
+		http.CanonicalHeaderKey(identity.Account): true,
+		http.CanonicalHeaderKey(identity.User):    true,
+		http.CanonicalHeaderKey(identity.Policy):  true,
+	}
+	if c.jwt != nil || c.tokenProvider != nil {
+		protected["Authorization"] = true
+	}
+	for k, v := range extra {
+		name := http.CanonicalHeaderKey(k)
+		if protected[name] {
+			continue
+		}
+		for existing := range base {
+			if existing != k && http.CanonicalHeaderKey(existing) == name {
+				delete(base, existing)
+			}
+		}
+		base[k] = v
+	}
+}
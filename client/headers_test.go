@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestServiceDefaultHeaders(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := &LambdaClient{
+		invoker: &mock,
+		user:    "test-user",
+	}
+	appStore := client.AppStore(WithDefaultHeaders(map[string]string{
+		"Api-Version":  "2",
+		"Feature":      "service",
+		"content-type": "application/graphql+json",
+	}))
+
+	ctx := ContextWithHeaders(context.Background(), map[string]string{"Feature": "request"})
+	_, err := appStore.GqlWithContext(ctx, MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	headers := payloadHeaders(t, &mock)
+	if headers["Api-Version"] != "2" {
+		t.Fatal("Missing service default header", headers)
+	}
+	if headers["content-type"] != "application/graphql+json" {
+		t.Fatal("Service defaults should override base headers", headers)
+	}
+	if headers["Feature"] != "request" {
+		t.Fatal("Request headers should override service defaults", headers)
+	}
+	if headers["LifeOmic-User"] != "test-user" {
+		t.Fatal("Missing base headers", headers)
+	}
+
+	marketplace := client.Marketplace()
+	_, err = marketplace.Gql(MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if _, ok := payloadHeaders(t, &mock)["Api-Version"]; ok {
+		t.Fatal("Default headers should be scoped to their sub-client")
+	}
+}
+
+type identityKey struct{}
+
+func TestIdentityFromContext(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := &LambdaClient{
+		invoker: &mock,
+		account: "default-account",
+		user:    "default-user",
+		identityFromContext: func(ctx context.Context) (string, string, bool) {
+			identity, ok := ctx.Value(identityKey{}).([2]string)
+			return identity[0], identity[1], ok
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), identityKey{}, [2]string{"ctx-account", "ctx-user"})
+	_, err := client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	headers := payloadHeaders(t, &mock)
+	if headers["LifeOmic-Account"] != "ctx-account" || headers["LifeOmic-User"] != "ctx-user" {
+		t.Fatal("Did not use identity from context", headers)
+	}
+
+	_, err = client.GqlWithContext(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	headers = payloadHeaders(t, &mock)
+	if headers["LifeOmic-Account"] != "default-account" || headers["LifeOmic-User"] != "default-user" {
+		t.Fatal("Did not fall back to client identity", headers)
+	}
+}
+
+func TestGqlContentType(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := &LambdaClient{
+		invoker: &mock,
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if contentType := payloadHeaders(t, &mock)["content-type"]; contentType != "application/json" {
+		t.Fatal("Expected default content type", contentType)
+	}
+
+	client.gqlContentType = "application/graphql+json"
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if contentType := payloadHeaders(t, &mock)["content-type"]; contentType != "application/graphql+json" {
+		t.Fatal("Did not override content type", contentType)
+	}
+}
+
+func TestWithoutContentType(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := (&LambdaClient{invoker: &mock}).Clone(WithoutContentType())
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	for name := range payloadHeaders(t, &mock) {
+		if strings.EqualFold(name, "content-type") {
+			t.Fatal("Expected no content type", payloadHeaders(t, &mock))
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "some-service:deployed/resource", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "text/plain")
+	_, _ = client.Do(req)
+	if contentType := payloadHeaders(t, &mock)["Content-Type"]; contentType != "text/plain" {
+		t.Fatal("Expected the request content type to be sent", payloadHeaders(t, &mock))
+	}
+}
+
+func TestHeaderPrecedence(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "statusCode": 200, "body": "{ \"data\": { \"result\": true } }" }`),
+		},
+	}
+	client := &LambdaClient{
+		invoker: &mock,
+		account: "base-account",
+		user:    "base-user",
+	}
+
+	ctx := ContextWithHeaders(context.Background(), map[string]string{
+		"Content-Type":     "application/graphql+json",
+		"lifeomic-account": "other-account",
+		"Api-Version":      "2",
+	})
+	_, err := client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	headers := payloadHeaders(t, &mock)
+	if _, ok := headers["content-type"]; ok || headers["Content-Type"] != "application/graphql+json" {
+		t.Fatal("Per-request headers should replace base headers of any case", headers)
+	}
+	if _, ok := headers["lifeomic-account"]; ok || headers["LifeOmic-Account"] != "base-account" {
+		t.Fatal("The account header should not be overridden", headers)
+	}
+	if headers["Api-Version"] != "2" {
+		t.Fatal("Missing additional header", headers)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "some-service:deployed/resource", strings.NewReader("a=b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("LifeOmic-User", "other-user")
+	req.Header.Set("Accept", "text/plain")
+	req.Header["X-Empty"] = []string{}
+	_, err = client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	headers = payloadHeaders(t, &mock)
+	if _, ok := headers["content-type"]; ok || headers["Content-Type"] != "application/x-www-form-urlencoded" {
+		t.Fatal("Request headers should replace base headers of any case", headers)
+	}
+	if _, ok := headers["Lifeomic-User"]; ok || headers["LifeOmic-User"] != "base-user" {
+		t.Fatal("The user header should not be overridden", headers)
+	}
+	if headers["Accept"] != "text/plain" {
+		t.Fatal("Missing additional header", headers)
+	}
+	if _, ok := headers["X-Empty"]; ok {
+		t.Fatal("Headers without a value should be skipped", headers)
+	}
+}
+
+func TestHeaderNames(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "statusCode": 200, "body": "{ \"data\": { \"result\": true } }" }`),
+		},
+	}
+	client := &LambdaClient{
+		invoker: &mock,
+		account: "test-account",
+		user:    "test-user",
+		rules:   map[string]bool{"readData": true},
+	}
+	WithHeaderNames(HeaderNames{Account: "X-Account", Policy: "LifeOmic-Policy-V2"})(client)
+
+	ctx := ContextWithHeaders(context.Background(), map[string]string{"x-account": "other-account"})
+	_, err := client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	headers := payloadHeaders(t, &mock)
+	if headers["X-Account"] != "test-account" || headers["LifeOmic-User"] != "test-user" {
+		t.Fatal("Expected the custom account header and default user header", headers)
+	}
+	if headers["LifeOmic-Policy-V2"] != `{"rules":{"readData":true}}` {
+		t.Fatal("Expected the custom policy header", headers)
+	}
+	if _, ok := headers["LifeOmic-Account"]; ok {
+		t.Fatal("Should not send the default account header", headers)
+	}
+	if _, ok := headers["LifeOmic-Policy"]; ok {
+		t.Fatal("Should not send the default policy header", headers)
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// defaultHTTPCacheEntries is the number of responses WithHTTPCache keeps
+// when it is not given a limit.
+const defaultHTTPCacheEntries = 256
+
+// httpCacheVaryHeaders are the request headers, besides the identity ones,
+// that commonly change the response to a GET and so are part of its key.
+var httpCacheVaryHeaders = []string{"Authorization", "Accept", "Accept-Encoding", "Accept-Language", "Cookie"}
+
+type cachedResponse struct {
+	key          string
+	etag         string
+	headers      map[string]string
+	body         []byte
+	decompressed bool
+}
+
+// httpCache holds the last ETag-tagged response to each GET made with Do,
+// evicting the least recently used response once it holds maxEntries.
+type httpCache struct {
+	mutex      sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	recent     list.List
+}
+
+func newHTTPCache(maxEntries int) *httpCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultHTTPCacheEntries
+	}
+	return &httpCache{maxEntries: maxEntries, entries: map[string]*list.Element{}}
+}
+
+// httpCacheKey identifies a GET request by its target, the identity it is
+// made with and the headers that negotiate its content, so responses are
+// never shared across callers or representations.
+func httpCacheKey(functionName string, path string, identity HeaderNames, headers map[string]string) string {
+	parts := []string{functionName, path}
+	names := append([]string{identity.Account, identity.User, identity.Policy}, httpCacheVaryHeaders...)
+	for _, name := range names {
+		value, _ := headerValue(headers, name)
+		parts = append(parts, value)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (h *httpCache) get(key string) *cachedResponse {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	element, ok := h.entries[key]
+	if !ok {
+		return nil
+	}
+	h.recent.MoveToFront(element)
+	return element.Value.(*cachedResponse)
+}
+
+// store caches a 200 response when it has an ETag, and drops any stale entry
+// when it does not.
+func (h *httpCache) store(key string, headers map[string]string, body []byte, decompressed bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if element, ok := h.entries[key]; ok {
+		h.recent.Remove(element)
+		delete(h.entries, key)
+	}
+	etag, ok := headerValue(headers, "ETag")
+	if !ok || etag == "" {
+		return
+	}
+	h.entries[key] = h.recent.PushFront(&cachedResponse{key: key, etag: etag, headers: headers, body: body, decompressed: decompressed})
+	for h.recent.Len() > h.maxEntries {
+		oldest := h.recent.Back()
+		h.recent.Remove(oldest)
+		delete(h.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestHTTPCache(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "statusCode": 200, "headers": { "etag": "\"v1\"" }, "body": "large resource" }`),
+		},
+	}
+	client := LambdaClient{
+		invoker:   &mock,
+		httpCache: newHTTPCache(0),
+	}
+	get := func() (*http.Response, Payload) {
+		req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal("Unexpected error", err)
+		}
+		var sent Payload
+		json.Unmarshal(mock.payload.Payload, &sent)
+		return resp, sent
+	}
+
+	_, sent := get()
+	if _, ok := sent.Headers["If-None-Match"]; ok {
+		t.Fatal("First request should not be conditional", sent.Headers)
+	}
+
+	mock.response = &lambda.InvokeOutput{
+		Payload: []byte(`{ "statusCode": 304, "body": "" }`),
+	}
+	resp, sent := get()
+	if sent.Headers["If-None-Match"] != `"v1"` {
+		t.Fatal("Did not revalidate with the ETag", sent.Headers)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(body) != "large resource" {
+		t.Fatal("Did not serve the cached body", resp.StatusCode, string(body))
+	}
+	if resp.Header["etag"][0] != `"v1"` {
+		t.Fatal("Did not serve the cached headers", resp.Header)
+	}
+
+	client.account = "other_account"
+	_, sent = get()
+	if _, ok := sent.Headers["If-None-Match"]; ok {
+		t.Fatal("Cache should not be shared across accounts", sent.Headers)
+	}
+
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+	req.Header.Set("Accept", "text/csv")
+	_, err := client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if _, ok := sent.Headers["If-None-Match"]; ok {
+		t.Fatal("Cache should not be shared across Accept headers", sent.Headers)
+	}
+}
+
+func TestHTTPCacheEviction(t *testing.T) {
+	cache := newHTTPCache(2)
+	headers := map[string]string{"ETag": `"v1"`}
+	cache.store("a", headers, nil, false)
+	cache.store("b", headers, nil, false)
+	cache.get("a")
+	cache.store("c", headers, nil, false)
+	if cache.get("b") != nil {
+		t.Fatal("Expected the least recently used entry to be evicted")
+	}
+	if cache.get("a") == nil || cache.get("c") == nil {
+		t.Fatal("Expected recently used entries to be kept")
+	}
+
+	cache.store("a", map[string]string{}, nil, false)
+	if cache.get("a") != nil || cache.recent.Len() != 1 {
+		t.Fatal("Expected a response without an ETag to drop the entry", cache.recent.Len())
+	}
+}
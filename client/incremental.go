@@ -0,0 +1,147 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+)
+
+// incrementalPayload is one part of an incremental delivery response, in
+// either the current format, where later parts list their results under
+// incremental, or the earlier one, where each part is a single result.
+type incrementalPayload struct {
+	Data        map[string]interface{} `json:"data"`
+	Items       []interface{}          `json:"items"`
+	Path        []interface{}          `json:"path"`
+	Errors      []GraphQLError         `json:"errors"`
+	Extensions  map[string]interface{} `json:"extensions"`
+	Incremental []incrementalPayload   `json:"incremental"`
+}
+
+// mergeIncremental decodes a multipart/mixed response produced by @defer and
+// @stream into a single GraphQL response. Lambda invocations can not stream,
+// so the whole response has already been buffered by the time the parts are
+// merged; the result is only available once every part has arrived. Bodies
+// of any other content type are returned unchanged.
+func mergeIncremental(headers map[string]string, body []byte) ([]byte, error) {
+	contentType, _ := headerValue(headers, "Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/mixed" {
+		return body, nil
+	}
+	merged := responseBody{}
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for first := true; ; first = false {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, decodeError(ErrDecodeBody, err, body)
+		}
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, decodeError(ErrDecodeBody, err, body)
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		var payload incrementalPayload
+		err = json.Unmarshal(data, &payload)
+		if err != nil {
+			return nil, decodeError(ErrDecodeBody, err, data)
+		}
+		if first {
+			merged.Data = payload.Data
+			merged.Errors = payload.Errors
+			merged.Extensions = payload.Extensions
+			continue
+		}
+		results := payload.Incremental
+		if results == nil {
+			results = []incrementalPayload{payload}
+		}
+		for _, result := range results {
+			merged.Errors = append(merged.Errors, result.Errors...)
+			err = applyIncremental(merged.Data, result)
+			if err != nil {
+				return nil, decodeError(ErrDecodeBody, err, data)
+			}
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// applyIncremental merges a deferred fragment's data, or a streamed list's
+// items, into data at the result's path.
+func applyIncremental(data map[string]interface{}, result incrementalPayload) error {
+	path := result.Path
+	if result.Items != nil {
+		// The last segment of a streamed result's path is the index of its
+		// first item, so the list itself lives at the parent path.
+		if len(path) < 2 {
+			return fmt.Errorf("Incremental path %v does not point into a list", result.Path)
+		}
+		path = path[:len(path)-1]
+		parent, err := walkPath(data, path[:len(path)-1])
+		if err != nil {
+			return err
+		}
+		object, ok := parent.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Incremental path %v does not point into a list", result.Path)
+		}
+		key := fmt.Sprint(path[len(path)-1])
+		list, ok := object[key].([]interface{})
+		if !ok && object[key] != nil {
+			return fmt.Errorf("Incremental path %v does not point into a list", result.Path)
+		}
+		object[key] = append(list, result.Items...)
+		return nil
+	}
+	target, err := walkPath(data, path)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		// The fragment's parent was nulled by an error, so there is nowhere
+		// to merge its data.
+		return nil
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Incremental path %v does not point to an object", result.Path)
+	}
+	for k, v := range result.Data {
+		object[k] = v
+	}
+	return nil
+}
+
+// walkPath returns the value at path in data, where string segments are
+// object keys and numeric segments are list indices.
+func walkPath(data map[string]interface{}, path []interface{}) (interface{}, error) {
+	var target interface{} = data
+	for _, segment := range path {
+		switch current := target.(type) {
+		case map[string]interface{}:
+			target = current[fmt.Sprint(segment)]
+		case []interface{}:
+			index, ok := segment.(float64)
+			if !ok || int(index) < 0 || int(index) >= len(current) {
+				return nil, fmt.Errorf("Invalid list index %v in incremental path", segment)
+			}
+			target = current[int(index)]
+		case nil:
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("Incremental path %v does not exist in the data", path)
+		}
+	}
+	return target, nil
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func multipartPayload(t *testing.T, parts ...string) []byte {
+	body := ""
+	for _, part := range parts {
+		body += "\r\n---\r\nContent-Type: application/json; charset=utf-8\r\n\r\n" + part
+	}
+	body += "\r\n-----\r\n"
+	payload, err := json.Marshal(responsePayload{
+		Body:       body,
+		StatusCode: 200,
+		Headers:    map[string]string{"content-type": `multipart/mixed; boundary="-"; deferSpec=20220824`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestGqlMergesIncrementalResponse(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: multipartPayload(t,
+				`{ "data": { "user": { "id": "1", "posts": [{ "id": "a" }] } }, "hasNext": true }`,
+				`{ "incremental": [{ "data": { "name": "Ada" }, "path": ["user"] }], "hasNext": true }`,
+				`{ "incremental": [{ "items": [{ "id": "b" }, { "id": "c" }], "path": ["user", "posts", 1] }], "hasNext": true }`,
+				`{ "data": { "email": null }, "path": ["user"], "errors": [{ "message": "No access" }], "hasNext": false }`,
+			),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	res, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || err.Error() != "No access" {
+		t.Fatal("Expected the incremental error", err)
+	}
+	user := (*res)["user"].(map[string]interface{})
+	if user["name"] != "Ada" || user["id"] != "1" {
+		t.Fatal("Did not merge deferred data", user)
+	}
+	if _, ok := user["email"]; !ok {
+		t.Fatal("Did not merge legacy incremental data", user)
+	}
+	if posts := user["posts"].([]interface{}); len(posts) != 3 || posts[2].(map[string]interface{})["id"] != "c" {
+		t.Fatal("Did not append streamed items", posts)
+	}
+}
+
+func TestMergeIncrementalErrors(t *testing.T) {
+	headers := map[string]string{"Content-Type": "multipart/mixed; boundary=-"}
+
+	body := []byte(`{ "data": { "result": true } }`)
+	merged, err := mergeIncremental(map[string]string{"Content-Type": "application/json"}, body)
+	if err != nil || string(merged) != string(body) {
+		t.Fatal("Should not touch non-multipart bodies", string(merged), err)
+	}
+
+	_, err = mergeIncremental(headers, []byte("\r\n---\r\n\r\nnot json\r\n-----\r\n"))
+	if !errors.Is(err, ErrDecodeBody) {
+		t.Fatal("Expected a decode error", err)
+	}
+
+	_, err = mergeIncremental(headers, []byte(strings.Join([]string{
+		"\r\n---\r\n\r\n" + `{ "data": { "user": "1" } }`,
+		"\r\n---\r\n\r\n" + `{ "incremental": [{ "items": [1], "path": ["user", 0] }] }`,
+		"\r\n-----\r\n",
+	}, "")))
+	if !errors.Is(err, ErrDecodeBody) {
+		t.Fatal("Expected an error for streamed items into a non-list", err)
+	}
+}
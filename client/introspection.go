@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+const INTROSPECTION_QUERY = `
+  query IntrospectionQuery {
+    __schema {
+      queryType { name }
+      mutationType { name }
+      subscriptionType { name }
+      types {
+        ...FullType
+      }
+      directives {
+        name
+        description
+        locations
+        args {
+          ...InputValue
+        }
+      }
+    }
+  }
+
+  fragment FullType on __Type {
+    kind
+    name
+    description
+    fields(includeDeprecated: true) {
+      name
+      description
+      args {
+        ...InputValue
+      }
+      type {
+        ...TypeRef
+      }
+      isDeprecated
+      deprecationReason
+    }
+    inputFields {
+      ...InputValue
+    }
+    interfaces {
+      ...TypeRef
+    }
+    enumValues(includeDeprecated: true) {
+      name
+      description
+      isDeprecated
+      deprecationReason
+    }
+    possibleTypes {
+      ...TypeRef
+    }
+  }
+
+  fragment InputValue on __InputValue {
+    name
+    description
+    type { ...TypeRef }
+    defaultValue
+  }
+
+  fragment TypeRef on __Type {
+    kind
+    name
+    ofType {
+      kind
+      name
+      ofType {
+        kind
+        name
+        ofType {
+          kind
+          name
+          ofType {
+            kind
+            name
+            ofType {
+              kind
+              name
+              ofType {
+                kind
+                name
+                ofType {
+                  kind
+                  name
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+`
+
+type IntrospectionResult struct {
+	QueryType        *IntrospectionTypeName
+	MutationType     *IntrospectionTypeName
+	SubscriptionType *IntrospectionTypeName
+	Types            []IntrospectionType
+	Directives       []IntrospectionDirective
+}
+
+type IntrospectionTypeName struct {
+	Name string
+}
+
+type IntrospectionType struct {
+	Kind          string
+	Name          string
+	Description   string
+	Fields        []IntrospectionField
+	InputFields   []IntrospectionInputValue
+	Interfaces    []IntrospectionTypeRef
+	EnumValues    []IntrospectionEnumValue
+	PossibleTypes []IntrospectionTypeRef
+}
+
+type IntrospectionField struct {
+	Name              string
+	Description       string
+	Args              []IntrospectionInputValue
+	Type              IntrospectionTypeRef
+	IsDeprecated      bool
+	DeprecationReason string
+}
+
+type IntrospectionInputValue struct {
+	Name         string
+	Description  string
+	Type         IntrospectionTypeRef
+	DefaultValue string
+}
+
+// IntrospectionTypeRef references a named type, wrapped by any number of
+// NON_NULL and LIST kinds through OfType.
+type IntrospectionTypeRef struct {
+	Kind   string
+	Name   string
+	OfType *IntrospectionTypeRef
+}
+
+type IntrospectionEnumValue struct {
+	Name              string
+	Description       string
+	IsDeprecated      bool
+	DeprecationReason string
+}
+
+type IntrospectionDirective struct {
+	Name        string
+	Description string
+	Locations   []string
+	Args        []IntrospectionInputValue
+}
+
+// Type returns the schema type with the given name, or nil if there is none.
+func (r *IntrospectionResult) Type(name string) *IntrospectionType {
+	for i := range r.Types {
+		if r.Types[i].Name == name {
+			return &r.Types[i]
+		}
+	}
+	return nil
+}
+
+// Introspect fetches the schema of the GraphQL service at uri using the
+// standard introspection query.
+func (c *LambdaClient) Introspect(ctx context.Context, uri string) (*IntrospectionResult, error) {
+	res, err := c.GqlWithContext(ctx, uri, INTROSPECTION_QUERY, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Schema IntrospectionResult `mapstructure:"__schema"`
+	}
+	err = mapstructure.Decode(res, &data)
+	if err != nil {
+		return nil, err
+	}
+	return &data.Schema, nil
+}
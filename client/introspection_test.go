@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+const MOCK_SCHEMA = `{ "data": { "__schema": {
+  "queryType": { "name": "Query" },
+  "mutationType": null,
+  "subscriptionType": null,
+  "types": [
+    { "kind": "OBJECT", "name": "Query", "description": null,
+      "fields": [
+        { "name": "app", "description": "Look up an app",
+          "args": [ { "name": "id", "description": null, "defaultValue": null,
+            "type": { "kind": "NON_NULL", "name": null, "ofType": { "kind": "SCALAR", "name": "ID", "ofType": null } } } ],
+          "type": { "kind": "OBJECT", "name": "App", "ofType": null },
+          "isDeprecated": false, "deprecationReason": null } ],
+      "inputFields": null, "interfaces": [], "enumValues": null, "possibleTypes": null },
+    { "kind": "ENUM", "name": "Product", "description": null, "fields": null, "inputFields": null,
+      "interfaces": null, "possibleTypes": null,
+      "enumValues": [ { "name": "LX", "description": null, "isDeprecated": false, "deprecationReason": null } ] }
+  ],
+  "directives": [ { "name": "skip", "description": null, "locations": ["FIELD"], "args": [] } ]
+} } }`
+
+func TestIntrospect(t *testing.T) {
+	payload, _ := json.Marshal(responsePayload{Body: MOCK_SCHEMA, StatusCode: 200})
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: payload,
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	schema, err := client.Introspect(context.Background(), "some_lambda:status/graphql")
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if schema.QueryType == nil || schema.QueryType.Name != "Query" {
+		t.Fatal("Did not decode query type", schema.QueryType)
+	}
+	if schema.MutationType != nil {
+		t.Fatal("Mutation type should be nil", schema.MutationType)
+	}
+
+	query := schema.Type("Query")
+	if query == nil || len(query.Fields) != 1 {
+		t.Fatal("Did not decode Query fields", query)
+	}
+	arg := query.Fields[0].Args[0]
+	if arg.Type.Kind != "NON_NULL" || arg.Type.OfType == nil || arg.Type.OfType.Name != "ID" {
+		t.Fatal("Did not decode nested type refs", arg.Type)
+	}
+
+	product := schema.Type("Product")
+	if product == nil || product.EnumValues[0].Name != "LX" {
+		t.Fatal("Did not decode enum values", product)
+	}
+	if schema.Type("Missing") != nil {
+		t.Fatal("Unknown types should be nil")
+	}
+	if len(schema.Directives) != 1 || schema.Directives[0].Locations[0] != "FIELD" {
+		t.Fatal("Did not decode directives", schema.Directives)
+	}
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// jwtClaims are the claims of a JWT the client reads its identity from.
+type jwtClaims struct {
+	Account       string `json:"account"`
+	CustomAccount string `json:"custom:account"`
+	Username      string `json:"cognito:username"`
+	Subject       string `json:"sub"`
+	// Expires is a NumericDate, which some issuers write with a fraction
+	// or an exponent.
+	Expires float64 `json:"exp"`
+}
+
+func (claims jwtClaims) account() string {
+	if claims.Account != "" {
+		return claims.Account
+	}
+	return claims.CustomAccount
+}
+
+// expires returns when the token expires, or false if it has no exp claim.
+func (claims jwtClaims) expires() (time.Time, bool) {
+	if claims.Expires == 0 {
+		return time.Time{}, false
+	}
+	seconds, fraction := math.Modf(claims.Expires)
+	return time.Unix(int64(seconds), int64(fraction*float64(time.Second))), true
+}
+
+func (claims jwtClaims) user() string {
+	if claims.Username != "" {
+		return claims.Username
+	}
+	return claims.Subject
+}
+
+// parseJWT decodes the claims of token without verifying its signature,
+// which is left to the gateway that authenticated the caller.
+func parseJWT(token string) (jwtClaims, error) {
+	var claims jwtClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, fmt.Errorf("%w: expected 3 parts, got %d", ErrInvalidJWT, len(parts))
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return claims, fmt.Errorf("%w: %v", ErrInvalidJWT, err)
+	}
+	err = json.Unmarshal(decoded, &claims)
+	if err != nil {
+		return claims, fmt.Errorf("%w: %v", ErrInvalidJWT, err)
+	}
+	return claims, nil
+}
+
+// jwtToken is a token set with WithJWT, along with its claims or the error
+// decoding them.
+type jwtToken struct {
+	token  string
+	claims jwtClaims
+	err    error
+}
+
+// jwtAuthorization returns the Authorization header forwarding the token set
+// with WithJWT, or an error if the token is invalid or has expired.
+func (c *LambdaClient) jwtAuthorization() (string, error) {
+	if c.jwt.err != nil {
+		return "", c.jwt.err
+	}
+	if exp, ok := c.jwt.claims.expires(); ok && !c.now().Before(exp) {
+		return "", fmt.Errorf("%w: expired at %s", ErrJWTExpired, exp.UTC().Format(time.RFC3339))
+	}
+	return "Bearer " + c.jwt.token, nil
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func testJWT(claims string) string {
+	return "eyJhbGciOiJIUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2lnbmF0dXJl"
+}
+
+func TestWithJWT(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": "{ \"data\": { \"result\": true } }" }`),
+		},
+	}
+	now := time.Unix(1600000000, 0)
+	client := LambdaClient{
+		invoker: &mock,
+		account: "base-account",
+		clock:   func() time.Time { return now },
+	}
+	token := testJWT(`{ "custom:account": "jwt-account", "cognito:username": "jwt-user", "sub": "1234", "exp": 1600000060 }`)
+	WithJWT(token)(&client)
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent, _ := DecodePayload(mock.payload.Payload)
+	if sent.Header("LifeOmic-Account") != "jwt-account" || sent.Header("LifeOmic-User") != "jwt-user" {
+		t.Fatal("Did not derive the identity from the token", sent.Headers)
+	}
+	if sent.Header("Authorization") != "Bearer "+token {
+		t.Fatal("Did not forward the token", sent.Headers)
+	}
+
+	ctx := ContextWithHeaders(context.Background(), map[string]string{"authorization": "Bearer other"})
+	_, err = client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent, _ = DecodePayload(mock.payload.Payload)
+	if _, ok := sent.Headers["authorization"]; ok || sent.Header("Authorization") != "Bearer "+token {
+		t.Fatal("The token should not be overridden", sent.Headers)
+	}
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+	req.Header.Set("Authorization", "Bearer other")
+	_, _ = client.Do(req)
+	if sent, _ = DecodePayload(mock.payload.Payload); sent.Header("Authorization") != "Bearer "+token {
+		t.Fatal("The token should not be overridden by Do", sent.Headers)
+	}
+
+	now = now.Add(time.Minute)
+	mock.hasBeenCalled = false
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrJWTExpired) || mock.hasBeenCalled {
+		t.Fatal("Expected an expired token error", err)
+	}
+
+	client = LambdaClient{invoker: &mock, clock: func() time.Time { return now }}
+	WithJWT(testJWT(`{ "sub": "1234", "exp": 1.60000012e9 }`))(&client)
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Expected a fractional exp claim to be accepted", err)
+	}
+	now = now.Add(time.Minute)
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrJWTExpired) {
+		t.Fatal("Expected a fractional exp claim to expire", err)
+	}
+
+	client = LambdaClient{invoker: &mock, account: "base-account"}
+	WithJWT(testJWT(`{ "sub": "1234" }`))(&client)
+	if client.account != "base-account" || client.user != "1234" {
+		t.Fatal("Expected the user to fall back to sub", client.account, client.user)
+	}
+	WithJWT(testJWT(`{ "account": "jwt-account" }`))(&client)
+	WithAccount("explicit-account")(&client)
+	if client.account != "explicit-account" {
+		t.Fatal("Options applied after WithJWT should take precedence", client.account)
+	}
+
+	WithJWT("not.a-jwt")(&client)
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrInvalidJWT) {
+		t.Fatal("Expected an invalid token error", err)
+	}
+}
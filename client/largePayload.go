@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Synchronous Lambda invocations are limited to 6MB for both the request and
+// the response payload.
+const maxPayloadSize = 6 * 1024 * 1024
+
+const largePayloadKeyPrefix = "phc-sdk-go/"
+
+// ObjectStore is the subset of the S3 API used by the large-payload fallback.
+//
+// When a request payload exceeds the Lambda limit it is written to the
+// configured bucket and the function is invoked with a pointer instead:
+//
+//	{ "s3Pointer": { "bucket": "my-bucket", "key": "phc-sdk-go/<random hex>" } }
+//
+// A function may answer with the same pointer shape in place of its normal
+// response, in which case the referenced object is fetched and used as the
+// response payload. Pointers to any other bucket than the configured one are
+// rejected with ErrInvalidPayloadPointer, so a function can not make the
+// client read objects it was not meant to.
+//
+// Once a RequestResponse invocation returns, its request object is deleted
+// if the store also has the DeleteObject method of the S3 client, which
+// needs the s3:DeleteObject permission on the bucket. Objects
+// sent with Event invocations, which the function reads later, and objects
+// whose deletion failed are left behind, so the bucket should also have a
+// lifecycle rule expiring objects under the phc-sdk-go/ prefix.
+type ObjectStore interface {
+	PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// objectDeleter is implemented by stores that can delete the request
+// objects they hold, such as the S3 client.
+type objectDeleter interface {
+	DeleteObject(context.Context, *s3.DeleteObjectInput, ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+type s3Pointer struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+type largePayloadPointer struct {
+	S3Pointer *s3Pointer `json:"s3Pointer"`
+}
+
+// putLargePayload uploads data and returns the pointer payload to send in
+// its place along with the key of the uploaded object.
+func (c *LambdaClient) putLargePayload(ctx context.Context, data []byte) ([]byte, string, error) {
+	id, err := c.randomHex(16)
+	if err != nil {
+		return nil, "", err
+	}
+	pointer := s3Pointer{
+		Bucket: c.largePayloadBucket,
+		Key:    largePayloadKeyPrefix + id,
+	}
+	_, err = c.store.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &pointer.Bucket,
+		Key:    &pointer.Key,
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	encoded, err := json.Marshal(&largePayloadPointer{S3Pointer: &pointer})
+	return encoded, pointer.Key, err
+}
+
+// deleteLargePayload deletes an uploaded request object, when the store can.
+// Failures are ignored, leaving the object to the bucket's lifecycle rule.
+func (c *LambdaClient) deleteLargePayload(ctx context.Context, key string) {
+	deleter, ok := c.store.(objectDeleter)
+	if !ok {
+		return
+	}
+	_, _ = deleter.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &c.largePayloadBucket,
+		Key:    &key,
+	})
+}
+
+// resolveLargePayload returns the payload referenced by an s3Pointer response,
+// or the payload itself when it is not a pointer.
+func (c *LambdaClient) resolveLargePayload(ctx context.Context, data []byte) ([]byte, error) {
+	var pointer largePayloadPointer
+	if json.Unmarshal(data, &pointer) != nil || pointer.S3Pointer == nil {
+		return data, nil
+	}
+	if pointer.S3Pointer.Bucket != c.largePayloadBucket {
+		return nil, fmt.Errorf("%w: bucket %q is not the large-payload bucket", ErrInvalidPayloadPointer, pointer.S3Pointer.Bucket)
+	}
+	obj, err := c.store.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &pointer.S3Pointer.Bucket,
+		Key:    &pointer.S3Pointer.Key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+	return ioutil.ReadAll(obj.Body)
+}
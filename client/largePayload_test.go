@@ -0,0 +1,157 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type MockStore struct {
+	objects map[string][]byte
+	deleted []string
+}
+
+func (m *MockStore) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput, rest ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	m.deleted = append(m.deleted, *input.Bucket+"/"+*input.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *MockStore) PutObject(ctx context.Context, input *s3.PutObjectInput, rest ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.objects[*input.Bucket+"/"+*input.Key] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *MockStore) GetObject(ctx context.Context, input *s3.GetObjectInput, rest ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewReader(m.objects[*input.Bucket+"/"+*input.Key])),
+	}, nil
+}
+
+func TestLargePayloadRequest(t *testing.T) {
+	store := MockStore{objects: map[string][]byte{}}
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := LambdaClient{
+		invoker:            &mock,
+		store:              &store,
+		largePayloadBucket: "large-payloads",
+	}
+
+	big := strings.Repeat("a", maxPayloadSize)
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{"var": big})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	var pointer largePayloadPointer
+	err = json.Unmarshal(mock.payload.Payload, &pointer)
+	if err != nil || pointer.S3Pointer == nil {
+		t.Fatal("Expected an s3 pointer payload", string(mock.payload.Payload))
+	}
+	if pointer.S3Pointer.Bucket != "large-payloads" {
+		t.Fatal("Did not use configured bucket", pointer.S3Pointer.Bucket)
+	}
+	stored, ok := store.objects["large-payloads/"+pointer.S3Pointer.Key]
+	if !ok {
+		t.Fatal("Payload was not uploaded", pointer.S3Pointer.Key)
+	}
+	if !strings.Contains(string(stored), big) {
+		t.Fatal("Uploaded payload is missing request body")
+	}
+	if len(store.deleted) != 1 || store.deleted[0] != "large-payloads/"+pointer.S3Pointer.Key {
+		t.Fatal("Expected the request object to be deleted after the invocation", store.deleted)
+	}
+
+	store.deleted = nil
+	client.invocationType = types.InvocationTypeEvent
+	mock.response = &lambda.InvokeOutput{StatusCode: 202}
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{"var": big})
+	if err != nil || len(store.deleted) != 0 {
+		t.Fatal("Expected the request object of an Event invocation to be kept", store.deleted, err)
+	}
+}
+
+func TestLargePayloadResponse(t *testing.T) {
+	store := MockStore{objects: map[string][]byte{
+		"large-payloads/response": []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+	}}
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"s3Pointer\": { \"bucket\": \"large-payloads\", \"key\": \"response\" } }"),
+		},
+	}
+	client := LambdaClient{
+		invoker:            &mock,
+		store:              &store,
+		largePayloadBucket: "large-payloads",
+	}
+
+	res, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if strings.Contains(string(mock.payload.Payload), "s3Pointer") {
+		t.Fatal("Small payloads should be sent inline", string(mock.payload.Payload))
+	}
+	if !(*res)["result"].(bool) {
+		t.Fatal("Did not return data from s3", *res)
+	}
+}
+
+func TestLargePayloadResponseOtherBucket(t *testing.T) {
+	store := MockStore{objects: map[string][]byte{
+		"other-bucket/secret": []byte(`{ "body": "secret" }`),
+	}}
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "s3Pointer": { "bucket": "other-bucket", "key": "secret" } }`),
+		},
+	}
+	client := LambdaClient{
+		invoker:            &mock,
+		store:              &store,
+		largePayloadBucket: "large-payloads",
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrInvalidPayloadPointer) {
+		t.Fatal("Expected pointers to other buckets to be rejected", err)
+	}
+}
+
+func TestLargePayloadBucketOnClone(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "s3Pointer": { "bucket": "large-payloads", "key": "response" } }`),
+		},
+	}
+	clone := (&LambdaClient{invoker: &mock}).Clone(WithLargePayloadBucket("large-payloads"))
+
+	big := strings.Repeat("a", maxPayloadSize)
+	_, err := clone.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{"var": big})
+	if !errors.Is(err, ErrMissingObjectStore) || mock.hasBeenCalled {
+		t.Fatal("Expected a missing object store error instead of a panic", err)
+	}
+
+	built := &LambdaClient{invoker: &mock, awsConfig: &aws.Config{Region: "us-east-1"}}
+	clone = built.Clone(WithLargePayloadBucket("large-payloads"))
+	if clone.store == nil || built.store != nil {
+		t.Fatal("Expected the clone to get its own S3 client")
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+// SortDirection is the order of a SortField.
+type SortDirection string
+
+const (
+	SortAscending  SortDirection = "ASC"
+	SortDescending SortDirection = "DESC"
+)
+
+// SortField orders list results by one field.
+type SortField struct {
+	Field     string
+	Direction SortDirection
+}
+
+// ListOptions holds the filter, sort and pagination arguments shared by list
+// queries across PHC services.
+type ListOptions struct {
+	Filter map[string]interface{}
+	Sort   []SortField
+	Page   PageArgs
+}
+
+// ToVariables returns the options as query variables. The filter is sent
+// under "filter", the sort fields under "sort" as a list of
+// { field, direction } objects, and the page arguments as described by
+// PageArgs. Empty options are left out.
+func (o ListOptions) ToVariables() (map[string]interface{}, error) {
+	variables, err := o.Page.Merge(map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	if len(o.Filter) > 0 {
+		variables["filter"] = o.Filter
+	}
+	if len(o.Sort) > 0 {
+		sort := make([]map[string]interface{}, len(o.Sort))
+		for i, field := range o.Sort {
+			direction := field.Direction
+			if direction == "" {
+				direction = SortAscending
+			}
+			sort[i] = map[string]interface{}{
+				"field":     field.Field,
+				"direction": string(direction),
+			}
+		}
+		variables["sort"] = sort
+	}
+	return variables, nil
+}
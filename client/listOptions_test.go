@@ -0,0 +1,44 @@
+package client
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestListOptionsToVariables(t *testing.T) {
+	options := ListOptions{
+		Filter: map[string]interface{}{"product": "LX"},
+		Sort: []SortField{
+			{Field: "name"},
+			{Field: "createdAt", Direction: SortDescending},
+		},
+		Page: PageArgs{First: 25, After: "cursor"},
+	}
+	variables, err := options.ToVariables()
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	expected := map[string]interface{}{
+		"filter": map[string]interface{}{"product": "LX"},
+		"sort": []map[string]interface{}{
+			{"field": "name", "direction": "ASC"},
+			{"field": "createdAt", "direction": "DESC"},
+		},
+		"first": 25,
+		"after": "cursor",
+	}
+	if !reflect.DeepEqual(variables, expected) {
+		t.Fatal("Unexpected variables", variables)
+	}
+
+	variables, err = ListOptions{}.ToVariables()
+	if err != nil || len(variables) != 0 {
+		t.Fatal("Empty options should produce no variables", variables, err)
+	}
+
+	_, err = ListOptions{Page: PageArgs{First: 1, Last: 1}}.ToVariables()
+	if !errors.Is(err, ErrInvalidPageArgs) {
+		t.Fatal("Expected invalid page args error", err)
+	}
+}
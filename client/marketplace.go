@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -14,10 +15,15 @@ import (
 type MarketplaceClient struct {
 	graphqlUrl string
 	client     graphqlClient
+	headers    map[string]string
 }
 
 func (self *MarketplaceClient) Gql(query string, variables map[string]interface{}) (*map[string]interface{}, error) {
-	return self.client.Gql(self.graphqlUrl, query, variables)
+	return self.GqlWithContext(context.Background(), query, variables)
+}
+
+func (self *MarketplaceClient) GqlWithContext(ctx context.Context, query string, variables map[string]interface{}) (*map[string]interface{}, error) {
+	return self.client.GqlWithContext(contextWithDefaultHeaders(ctx, self.headers), self.graphqlUrl, query, variables)
 }
 
 const GET_PUBLISHED_APP_TILE_MODULE = `
@@ -0,0 +1,630 @@
+package client
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// Option configures optional LambdaClient behavior. Options are passed to
+// BuildClient and applied in order after the defaults have been set up.
+type Option func(*LambdaClient)
+
+// WithAccount sets the LifeOmic account requests are made in, replacing the
+// one given to BuildClient. It is mostly useful with Clone.
+func WithAccount(account string) Option {
+	return func(c *LambdaClient) {
+		c.account = account
+	}
+}
+
+// WithUser sets the LifeOmic user requests are made as, replacing the one
+// given to BuildClient. It is mostly useful with Clone.
+func WithUser(user string) Option {
+	return func(c *LambdaClient) {
+		c.user = user
+	}
+}
+
+// WithRules sets the policy rules sent with every request, replacing the
+// ones given to BuildClient. It is mostly useful with Clone.
+func WithRules(rules map[string]bool) Option {
+	return func(c *LambdaClient) {
+		c.rules = rules
+	}
+}
+
+// WithHeaderNames changes the names of the headers the account, user and
+// policy are sent in, for gateways that expect other names than
+// LifeOmic-Account, LifeOmic-User and LifeOmic-Policy. Names left empty keep
+// their default.
+func WithHeaderNames(names HeaderNames) Option {
+	return func(c *LambdaClient) {
+		c.headerNames = names
+	}
+}
+
+// WithPolicyRules sets policy rules whose values are not plain booleans, such
+// as attribute-scoped rules, which are sent in the LifeOmic-Policy header as
+// given. They are merged with the boolean rules given to BuildClient or
+// WithRules, taking precedence for rules set in both.
+func WithPolicyRules(rules map[string]interface{}) Option {
+	return func(c *LambdaClient) {
+		c.richRules = rules
+	}
+}
+
+// WithLargePayloadBucket enables the large-payload fallback. Requests that
+// exceed the synchronous Lambda payload limit are uploaded to the given S3
+// bucket and replaced by a pointer, and pointer responses are fetched back
+// from S3. See ObjectStore for the pointer schema.
+func WithLargePayloadBucket(bucket string) Option {
+	return func(c *LambdaClient) {
+		c.largePayloadBucket = bucket
+	}
+}
+
+// TokenProvider returns a bearer token for the request being made with ctx.
+type TokenProvider func(ctx context.Context) (string, error)
+
+// WithTokenProvider sets a callback that is called before every request to
+// obtain a fresh token, which is sent as a bearer token in the Authorization
+// header. Any caching is left to the provider.
+func WithTokenProvider(provider TokenProvider) Option {
+	return func(c *LambdaClient) {
+		c.tokenProvider = provider
+	}
+}
+
+// WithJWT derives the identity of the client from token, the JWT of an
+// authenticated caller, and forwards the token as a bearer token in the
+// Authorization header. LifeOmic-Account is taken from the account claim, or
+// custom:account when that is missing, and LifeOmic-User from the
+// cognito:username claim, or sub when that is missing; claims the token does
+// not have leave the account or user as they were. The token's identity
+// replaces the account and user given to BuildClient or set by earlier
+// options, since the function authorizes the caller the token belongs to;
+// WithAccount or WithUser applied after WithJWT replace it in turn. The
+// token's signature is
+// not verified, but its exp claim is: requests fail with ErrJWTExpired once
+// it has passed, and with ErrInvalidJWT when the token can not be decoded.
+// A token provider set with WithTokenProvider takes precedence for the
+// Authorization header.
+func WithJWT(token string) Option {
+	return func(c *LambdaClient) {
+		claims, err := parseJWT(token)
+		if err == nil {
+			if account := claims.account(); account != "" {
+				c.account = account
+			}
+			if user := claims.user(); user != "" {
+				c.user = user
+			}
+		}
+		c.jwt = &jwtToken{token: token, claims: claims, err: err}
+	}
+}
+
+// WithInvokeInputMutator sets a function that may adjust the InvokeInput just
+// before it is sent, as an escape hatch for fields the client does not
+// manage itself. FunctionName and Payload are already set when the mutator
+// runs and should not be overwritten.
+func WithInvokeInputMutator(mutator func(*lambda.InvokeInput)) Option {
+	return func(c *LambdaClient) {
+		c.invokeInputMutator = mutator
+	}
+}
+
+// WithClientContext sets data that is passed to the function as the Lambda
+// ClientContext, available through the runtime context rather than headers.
+// The value is JSON encoded then base64 encoded, and requests fail with
+// ErrClientContextTooLarge if the result exceeds 3583 bytes.
+func WithClientContext(clientContext map[string]interface{}) Option {
+	return func(c *LambdaClient) {
+		c.clientContext = clientContext
+	}
+}
+
+// WithHTTPClient replaces the HTTP client the AWS SDK uses to call Lambda
+// (and S3 for large payloads), for example to route through a local proxy.
+//
+// This is intended for development against test proxies only. A client with
+// a transport that skips TLS verification must never be used in production.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *LambdaClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithResponseDecompression advertises gzip support to functions with an
+// Accept-Encoding header and transparently decompresses responses that come
+// back with a gzip Content-Encoding. The header is only sent in this mode so
+// functions never return bodies the client can not read.
+func WithResponseDecompression() Option {
+	return func(c *LambdaClient) {
+		c.decompressResponses = true
+	}
+}
+
+// ServiceOption configures a sub-client such as AppStore or Marketplace.
+type ServiceOption func(*serviceOptions)
+
+type serviceOptions struct {
+	headers map[string]string
+}
+
+func buildServiceOptions(opts []ServiceOption) serviceOptions {
+	var options serviceOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithDefaultHeaders sets headers sent with every request the sub-client
+// makes. They override the client's base headers but are themselves
+// overridden by headers from ContextWithHeaders.
+func WithDefaultHeaders(headers map[string]string) ServiceOption {
+	return func(o *serviceOptions) {
+		o.headers = headers
+	}
+}
+
+// IdentityResolver extracts the account and user for a request from its
+// context. It returns false when ctx carries no identity.
+type IdentityResolver func(ctx context.Context) (account string, user string, ok bool)
+
+// WithIdentityFromContext resolves the LifeOmic account and user of each
+// request from its context, so one client can serve requests on behalf of
+// many callers. The account and user given to BuildClient are used when the
+// resolver returns false.
+func WithIdentityFromContext(resolver IdentityResolver) Option {
+	return func(c *LambdaClient) {
+		c.identityFromContext = resolver
+	}
+}
+
+// WithMaxQueryDepth rejects queries whose fields are nested more than depth
+// levels deep with ErrQueryTooComplex before they are sent. The check is
+// purely structural and does not need the schema.
+func WithMaxQueryDepth(depth int) Option {
+	return func(c *LambdaClient) {
+		c.maxQueryDepth = depth
+	}
+}
+
+// WithXRayTracing makes invocations part of an X-Ray trace. The trace id is
+// taken from ContextWithTraceID, then from _X_AMZN_TRACE_ID when running in
+// a Lambda function, and generated otherwise. It is set on the Invoke API
+// call, so Lambda links the function's segment to the trace, and is also
+// forwarded as an X-Amzn-Trace-Id header in the payload.
+//
+// The client does not read OpenTelemetry spans itself. Callers that trace
+// with OpenTelemetry should use its X-Ray propagator to produce the header
+// value and pass it in with ContextWithTraceID.
+func WithXRayTracing() Option {
+	return func(c *LambdaClient) {
+		c.xrayTracing = true
+	}
+}
+
+// WithCredentialCheck makes BuildClient retrieve AWS credentials up front and
+// fail if they can not be resolved, instead of failing on the first request.
+func WithCredentialCheck() Option {
+	return func(c *LambdaClient) {
+		c.checkCredentials = true
+	}
+}
+
+// WithPartialErrorHandler sets a function that is called with the errors of
+// any GraphQL response that holds both data and errors, so degraded
+// responses can be logged or alerted on.
+func WithPartialErrorHandler(handler func(errs []GraphQLError)) Option {
+	return func(c *LambdaClient) {
+		c.partialErrorHandler = handler
+	}
+}
+
+// WithInvocationType sets the Lambda InvocationType used for every request.
+// The default is RequestResponse. Event invocations are queued by Lambda and
+// return no body, so once the event is queued GraphQL requests return no
+// data and no error, with GqlResponse.Accepted and GraphQLResponse.Accepted
+// set, and Do returns an empty 202 Accepted response. DryRun
+// invocations only check that the call would be allowed and produce no
+// response that Gql or Do can decode. The Invoke API status is checked
+// against the type (200, 202 and 204 respectively) and a mismatch returns
+// ErrUnexpectedInvokeStatus.
+func WithInvocationType(invocationType types.InvocationType) Option {
+	return func(c *LambdaClient) {
+		c.invocationType = invocationType
+	}
+}
+
+// PayloadSigner computes a signature header for a request body.
+type PayloadSigner func(body []byte) (headerName string, headerValue string, err error)
+
+// WithPayloadSigner signs every request so the function can verify it was
+// not tampered with. The signer is called with the request body (the
+// marshaled GraphQL request for Gql) and the header it returns is added to
+// the request. The signing algorithm is up to the caller.
+func WithPayloadSigner(signer PayloadSigner) Option {
+	return func(c *LambdaClient) {
+		c.payloadSigner = signer
+	}
+}
+
+// WithGqlContentType sets the content-type header sent with GraphQL requests,
+// for gateways that expect something other than the default
+// application/json. Requests made with Do are not affected.
+func WithGqlContentType(contentType string) Option {
+	return func(c *LambdaClient) {
+		c.gqlContentType = contentType
+	}
+}
+
+// WithoutContentType stops the client from sending its default
+// content-type: application/json header, for functions that reject requests
+// carrying one. A content-type set on the request given to Do, or with
+// WithGqlContentType, is still sent.
+func WithoutContentType() Option {
+	return func(c *LambdaClient) {
+		c.omitContentType = true
+	}
+}
+
+// WithDefaultVariables sets variables that are sent with every GraphQL
+// request, such as a project or dataset id shared by many queries. A
+// variable passed to the call itself takes precedence over a default of the
+// same name.
+func WithDefaultVariables(variables map[string]interface{}) Option {
+	return func(c *LambdaClient) {
+		c.defaultVariables = variables
+	}
+}
+
+// WithStrictErrors makes any GraphQL error fail the request. By default a
+// response holding both data and errors returns the partial data along with
+// the errors; in strict mode the data is dropped and only the errors are
+// returned.
+func WithStrictErrors() Option {
+	return func(c *LambdaClient) {
+		c.strictErrors = true
+	}
+}
+
+// WithPathRewriter sets a function that rewrites the path of every request,
+// for example to add a tenant prefix for gateway routing. It is called with
+// the path part of the uri given to Gql or the URL given to Do, and its
+// result is sent as the path in the payload.
+func WithPathRewriter(rewriter func(path string) string) Option {
+	return func(c *LambdaClient) {
+		c.pathRewriter = rewriter
+	}
+}
+
+// WithFunctionOverride sets a function that remaps the function name of every
+// request, for example to send a share of traffic to a canary by appending a
+// suffix. It is called with the function name parsed from the uri given to
+// Gql or the URL given to Do, and the function it returns is invoked. A
+// PreparedQuery remaps its function once, when it is prepared.
+func WithFunctionOverride(override func(functionName string) string) Option {
+	return func(c *LambdaClient) {
+		c.functionOverride = override
+	}
+}
+
+// WithRequestIDCheck tags every request with an X-Request-Id header and, when
+// the function echoes the header back in its response, logs a warning if the
+// id does not match, to catch responses delivered to the wrong request. The
+// id is taken from ContextWithRequestID and generated otherwise, as a random
+// UUID unless a generator is set with WithIDGenerator.
+func WithRequestIDCheck() Option {
+	return func(c *LambdaClient) {
+		c.checkRequestIDs = true
+	}
+}
+
+// WithLogger sets the logger warnings are written to, such as mismatched
+// request ids and variables that WithVariableCoercion expects to encode
+// badly. By default they are written to the standard logger.
+func WithLogger(logger *log.Logger) Option {
+	return func(c *LambdaClient) {
+		c.logger = logger
+	}
+}
+
+// WithIDGenerator sets the function that generates request ids for requests
+// whose context has none, so they match the id scheme used in the rest of a
+// service's logs. Every request is then tagged with an X-Request-Id header,
+// whether or not WithRequestIDCheck is set; that option only adds the check
+// of the echoed id. With WithRequestIDCheck alone ids are random version 4
+// UUIDs.
+func WithIDGenerator(generate func() string) Option {
+	return func(c *LambdaClient) {
+		c.idGenerator = generate
+	}
+}
+
+// WithAWSRetryer replaces the retryer the AWS SDK uses for Lambda (and S3 for
+// large payloads) calls. By default the SDK retries throttling and transient
+// errors up to 3 attempts with exponential backoff. Callers that retry
+// requests themselves should use WithoutAWSRetries instead so delays do not
+// compound across two layers of retries.
+func WithAWSRetryer(retryer aws.Retryer) Option {
+	return func(c *LambdaClient) {
+		c.awsRetryer = retryer
+	}
+}
+
+// WithNonIdempotentRetries lets the AWS SDK retry failed invocations made by
+// Do for any HTTP method. By default, like net/http, Do only lets requests be
+// retried when their method is idempotent (GET, HEAD, OPTIONS, TRACE, PUT
+// and DELETE) or they carry an Idempotency-Key header, since a retried POST
+// or PATCH may run twice. GraphQL requests are not affected.
+func WithNonIdempotentRetries() Option {
+	return func(c *LambdaClient) {
+		c.retryNonIdempotent = true
+	}
+}
+
+// WithoutAWSRetries disables the AWS SDK retryer so every request makes a
+// single Invoke attempt, leaving retries entirely to the caller.
+func WithoutAWSRetries() Option {
+	return WithAWSRetryer(aws.NopRetryer{})
+}
+
+// WithVariableCoercion normalizes GraphQL variables before they are sent, to
+// avoid confusing server-side errors for common mismatches. time.Time values
+// are sent as RFC 3339 strings in UTC, keeping any fractional seconds, and
+// json.Number values as plain numbers, with integers too large for an int64
+// sent exactly as given. Structs, which are sent with their Go field names
+// unless they have json tags, and values that can not be encoded are logged
+// as warnings.
+func WithVariableCoercion() Option {
+	return func(c *LambdaClient) {
+		c.coerceVariables = true
+	}
+}
+
+// WithOmitNullVariables drops variables whose value is nil before a request is
+// sent. GraphQL treats an explicit null differently from an absent variable,
+// and a nil left in the map for an optional argument is often rejected with
+// an invalid value error. Only top-level variables are dropped; callers that
+// mean to send an explicit null should leave this off.
+func WithOmitNullVariables() Option {
+	return func(c *LambdaClient) {
+		c.omitNullVariables = true
+	}
+}
+
+// WithRequestTimeout limits how long each request made with Gql or Do may
+// take, on top of any deadline of the caller's context, and sends the
+// remaining time to the function like a context deadline. Requests that run
+// out of time fail with an error matching both ErrRequestTimeout and
+// context.DeadlineExceeded, so they can be told apart from requests whose
+// own context expired, which fail with context.DeadlineExceeded alone, and
+// from requests cancelled by the caller, which fail with context.Canceled.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *LambdaClient) {
+		c.requestTimeout = timeout
+	}
+}
+
+// WithConnectTimeout limits how long establishing a connection to the Lambda
+// API may take, 30 seconds by default. Connecting normally takes
+// milliseconds, so a few seconds is enough to fail fast on network problems.
+// It has no effect when WithHTTPClient is used.
+func WithConnectTimeout(timeout time.Duration) Option {
+	return func(c *LambdaClient) {
+		c.connectTimeout = timeout
+	}
+}
+
+// WithResponseTimeout limits how long to wait for the Lambda API to respond
+// once a request has been sent. There is no limit by default. Lambda only
+// responds when the function returns, so the timeout should leave room for
+// the function's own timeout plus a cold start of up to several seconds.
+// It has no effect when WithHTTPClient is used.
+func WithResponseTimeout(timeout time.Duration) Option {
+	return func(c *LambdaClient) {
+		c.responseTimeout = timeout
+	}
+}
+
+// WithBase64Bodies makes Do send request bodies base64 encoded with
+// isBase64Encoded set, as API Gateway does for binary media, so bodies that
+// are not valid UTF-8 reach the function intact. The function must decode
+// the body itself. GraphQL requests are always sent as plain JSON.
+func WithBase64Bodies() Option {
+	return func(c *LambdaClient) {
+		c.base64Bodies = true
+	}
+}
+
+// WithHeaderAllowlist limits the request headers Do forwards to the function
+// to the named ones, matched case-insensitively. Headers the client sets
+// itself, such as the identity, policy and authorization headers, are always
+// sent. By default every request header is forwarded.
+func WithHeaderAllowlist(names []string) Option {
+	return func(c *LambdaClient) {
+		c.headerAllowlist = make(map[string]bool, len(names))
+		for _, name := range names {
+			c.headerAllowlist[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// WithCircuitBreaker stops sending requests to a function that keeps failing.
+// After settings.FailureThreshold consecutive failed invocations the
+// function's circuit opens and requests fail with ErrCircuitOpen. Once
+// settings.OpenTimeout has passed a single request is let through; the
+// circuit closes if it succeeds and opens again if it fails. Failures are
+// invoke errors and function errors; GraphQL errors and HTTP error statuses
+// in a response do not count, nor do requests abandoned by their context.
+// Each function name has its own circuit.
+func WithCircuitBreaker(settings CircuitBreakerSettings) Option {
+	return func(c *LambdaClient) {
+		c.circuitBreaker = newCircuitBreaker(settings)
+	}
+}
+
+// WithResponseTransform sets a function that rewrites the body of every
+// GraphQL response before it is decoded, to adapt to backends that do not
+// quite follow the spec, such as one wrapping the response in an envelope
+// of its own. It runs after base64 and gzip decoding. Responses to Do are
+// not transformed.
+func WithResponseTransform(transform func(raw []byte) ([]byte, error)) Option {
+	return func(c *LambdaClient) {
+		c.responseTransform = transform
+	}
+}
+
+// WithResponseEnvelopeKey decodes GraphQL responses from gateways that nest
+// the response under a key of their own, such as {"result": {"data": ...}},
+// by reading data and errors from under key instead of the top level of the
+// body. A response without the key fails with ErrDecodeBody. A transform set
+// with WithResponseTransform sees the body from under the key. Responses to
+// Do are not affected.
+func WithResponseEnvelopeKey(key string) Option {
+	return func(c *LambdaClient) {
+		c.responseEnvelopeKey = key
+	}
+}
+
+// WithHTTPCache caches the responses to GET requests made with Do that carry
+// an ETag, and revalidates them with If-None-Match on the next identical
+// request. When the function answers 304 Not Modified the cached response is
+// returned as a 200 in its place. Responses are cached per account, user,
+// policy and authorization, and per Accept, Accept-Encoding, Accept-Language
+// and Cookie header. At most maxEntries responses are kept, evicting the
+// least recently used; 0 or less keeps 256. Requests that set If-None-Match
+// themselves bypass the cache.
+func WithHTTPCache(maxEntries int) Option {
+	return func(c *LambdaClient) {
+		c.httpCache = newHTTPCache(maxEntries)
+	}
+}
+
+// WithMaxReportedErrors bounds the GraphQL errors reported for a single
+// response to the first n, so a failed bulk mutation can not flood the logs.
+// The partial error handler, GqlRawData and GqlResponse.Errors only see the
+// first n errors, and the returned error notes how many were omitted. The
+// default, or an n of zero or less, reports every error.
+func WithMaxReportedErrors(n int) Option {
+	return func(c *LambdaClient) {
+		c.maxReportedErrors = n
+	}
+}
+
+// WithMaxConcurrency caps the number of Lambda invocations the client runs at
+// once to n, to avoid exhausting the reserved concurrency of the functions it
+// calls. Requests beyond the cap wait for a running invocation to finish, or
+// fail with the context error if their context is done first. Waiting
+// requests are served in the order they arrived, and the time a request
+// waited is reported in GqlResponse.QueueWait. Clones of the client share
+// the cap.
+func WithMaxConcurrency(n int) Option {
+	return func(c *LambdaClient) {
+		if n > 0 {
+			c.invokeSlots = newSemaphore(n)
+		}
+	}
+}
+
+// WithDryRunRecorder makes the client record every invocation it would make
+// in recorder, and answer it with the recorder's canned response, without
+// calling AWS. It is meant for checking what orchestration code would send
+// while still exercising the real client, including its headers, policy and
+// payload encoding. Oversized payloads are recorded as they are rather than
+// being uploaded to the large-payload bucket.
+func WithDryRunRecorder(recorder *DryRunRecorder) Option {
+	return func(c *LambdaClient) {
+		c.dryRun = recorder
+	}
+}
+
+// WithResponseValidator sets a function that checks every response returned
+// by Do, to enforce contracts such as required headers or content types in
+// one place. It is called after the response is built, whatever its status
+// code, and when it returns an error Do returns that error, wrapped, instead
+// of the response. It must not consume the response body.
+func WithResponseValidator(validator func(resp *http.Response) error) Option {
+	return func(c *LambdaClient) {
+		c.responseValidator = validator
+	}
+}
+
+// WithDeadlineHeader changes how the time left before a request's context
+// deadline is sent to the function, for backends that expect another
+// convention than the default X-Timeout-Ms header holding milliseconds. An
+// empty name keeps X-Timeout-Ms and a nil format keeps MillisecondsDeadline;
+// GRPCDeadline matches the grpc-timeout header.
+func WithDeadlineHeader(name string, format DeadlineFormat) Option {
+	return func(c *LambdaClient) {
+		c.deadlineHeaderName = name
+		c.deadlineFormat = format
+	}
+}
+
+// WithDump writes every invocation payload sent, and every response payload
+// received, to w for debugging. Payloads longer than the limit set with
+// WithDumpBodyLimit, 4KB by default, are truncated. Payloads include the
+// request headers, so the dump should not be kept where credentials must
+// not end up.
+func WithDump(w io.Writer) Option {
+	return func(c *LambdaClient) {
+		c.dump = &dumper{w: w}
+	}
+}
+
+// WithDumpBodyLimit sets the number of bytes of each payload written by
+// WithDump, after which it is cut with a "...(truncated)" marker. A limit of
+// zero or less writes payloads in full.
+func WithDumpBodyLimit(n int) Option {
+	return func(c *LambdaClient) {
+		if n <= 0 {
+			n = -1
+		}
+		c.dumpBodyLimit = n
+	}
+}
+
+// WithResultHook sets a function called with a pointer to every result
+// decoded into a caller's type, that is each result of Query and each node
+// returned by Cursor.Next, to normalize results in one place, such as
+// filling in defaults or resolving relative URLs. It may modify the result.
+// When it returns an error that error is returned, wrapped, instead of the
+// result. Methods returning undecoded data, such as Gql and Execute, do not
+// call it.
+func WithResultHook(hook func(v interface{}) error) Option {
+	return func(c *LambdaClient) {
+		c.resultHook = hook
+	}
+}
+
+// WithProfile loads the AWS configuration from the named profile of the
+// shared config and credentials files instead of the default one, to target
+// another account without changing the environment. Like WithRegion, it only
+// applies to the configuration loaded by BuildClient and BuildClientFromEnv,
+// and has no effect on Clone.
+func WithProfile(name string) Option {
+	return func(c *LambdaClient) {
+		c.profile = name
+	}
+}
+
+// WithRegion sets the AWS region functions are invoked in, taking precedence
+// over the region of the environment and of the profile set WithProfile. It
+// only applies to the configuration loaded by BuildClient and
+// BuildClientFromEnv, and has no effect on Clone.
+func WithRegion(region string) Option {
+	return func(c *LambdaClient) {
+		c.region = region
+	}
+}
@@ -0,0 +1,286 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	var requested *http.Request
+	httpClient := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requested = req
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(bytes.NewBufferString("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}")),
+			}, nil
+		}),
+	}
+
+	client, err := BuildClient("test-account", "test-user", map[string]bool{}, WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	res, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if requested == nil {
+		t.Fatal("Custom HTTP client was not used")
+	}
+	if requested.URL.Host != "lambda.us-east-1.amazonaws.com" {
+		t.Fatal("Unexpected request host", requested.URL.Host)
+	}
+	if !(*res)["result"].(bool) {
+		t.Fatal("Did not return data", *res)
+	}
+}
+
+func TestWithProfile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(configFile, []byte("[profile dev]\nregion = eu-west-1\naws_access_key_id = test\naws_secret_access_key = test\n"), 0600)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "")
+
+	var requested *http.Request
+	httpClient := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requested = req
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(bytes.NewBufferString("{ \"body\": \"{ \\\"data\\\": {}}\"}")),
+			}, nil
+		}),
+	}
+
+	client, err := BuildClient("test-account", "test-user", nil, WithHTTPClient(httpClient), WithProfile("dev"), WithRegion("ap-south-1"))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, nil)
+	if err != nil {
+		t.Fatal("Expected the profile credentials to be used", err)
+	}
+	if requested.URL.Host != "lambda.ap-south-1.amazonaws.com" {
+		t.Fatal("Expected WithRegion to take precedence", requested.URL.Host)
+	}
+	if !strings.Contains(requested.Header.Get("Authorization"), "Credential=test/") {
+		t.Fatal("Expected the profile credentials to sign the request", requested.Header.Get("Authorization"))
+	}
+}
+
+func TestWithCredentialCheck(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_CONFIG_FILE", t.TempDir()+"/missing")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", t.TempDir()+"/missing")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	_, err := BuildClient("test-account", "test-user", map[string]bool{})
+	if err != nil {
+		t.Fatal("Credentials should not be checked by default", err)
+	}
+
+	_, err = BuildClient("test-account", "test-user", map[string]bool{}, WithCredentialCheck())
+	if err == nil || !strings.Contains(err.Error(), "Unable to resolve AWS credentials") {
+		t.Fatal("Expected a credentials error", err)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	_, err = BuildClient("test-account", "test-user", map[string]bool{}, WithCredentialCheck())
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+}
+
+func TestWithAWSRetryer(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	attempts := 0
+	httpClient := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return &http.Response{
+				StatusCode: 503,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(bytes.NewBufferString("{}")),
+			}, nil
+		}),
+	}
+
+	client, err := BuildClient("test-account", "test-user", map[string]bool{}, WithHTTPClient(httpClient), WithoutAWSRetries())
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || attempts != 1 {
+		t.Fatal("Expected a single attempt without AWS retries", attempts, err)
+	}
+
+	attempts = 0
+	retryer := retry.NewStandard(func(o *retry.StandardOptions) {
+		o.MaxAttempts = 2
+		o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) {
+			return 0, nil
+		})
+	})
+	client, err = BuildClient("test-account", "test-user", map[string]bool{}, WithHTTPClient(httpClient), WithAWSRetryer(retryer))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || attempts != 2 {
+		t.Fatal("Expected the custom retryer to be used", attempts, err)
+	}
+}
+
+func TestTransportTimeouts(t *testing.T) {
+	client := LambdaClient{
+		connectTimeout:  2 * time.Second,
+		responseTimeout: time.Minute,
+	}
+	httpClient := client.buildHTTPClient()
+	if httpClient.GetDialer().Timeout != 2*time.Second {
+		t.Fatal("Did not set connect timeout", httpClient.GetDialer().Timeout)
+	}
+	if httpClient.GetTransport().ResponseHeaderTimeout != time.Minute {
+		t.Fatal("Did not set response timeout", httpClient.GetTransport().ResponseHeaderTimeout)
+	}
+
+	client.connectTimeout = 0
+	if httpClient := client.buildHTTPClient(); httpClient.GetDialer().Timeout != 30*time.Second {
+		t.Fatal("Unset timeouts should keep the SDK default", httpClient.GetDialer().Timeout)
+	}
+}
+
+func TestContextErrors(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	started := make(chan struct{}, 1)
+	httpClient := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			started <- struct{}{}
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}),
+	}
+	client, err := BuildClient("test-account", "test-user", map[string]bool{}, WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err = client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != context.Canceled {
+		t.Fatal("Expected a plain context.Canceled", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "some-service:deployed/resource", nil)
+	_, err = client.Do(req)
+	expectStarted(t, started)
+	if err != context.DeadlineExceeded {
+		t.Fatal("Expected a plain context.DeadlineExceeded", err)
+	}
+}
+
+// expectStarted fails the test unless a request has signalled started,
+// rather than blocking forever when none was made.
+func expectStarted(t *testing.T, started <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the request to be sent")
+	}
+}
+
+// blockingInvoker never answers, returning once the context is done.
+type blockingInvoker struct {
+	started chan struct{}
+}
+
+func (b *blockingInvoker) Invoke(ctx context.Context, input *lambda.InvokeInput, rest ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	b.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRequestTimeout(t *testing.T) {
+	invoker := blockingInvoker{started: make(chan struct{}, 1)}
+	client := LambdaClient{
+		invoker: &invoker,
+	}
+	WithRequestTimeout(10 * time.Millisecond)(&client)
+
+	_, err := client.GqlWithContext(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	expectStarted(t, invoker.started)
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrRequestTimeout) {
+		t.Fatal("Expected the request timeout", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Fatal("A timeout should not look like a cancellation", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-invoker.started
+		cancel()
+	}()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "some-service:deployed/resource", nil)
+	client.requestTimeout = time.Minute
+	_, err = client.Do(req)
+	if !errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("Expected the caller's cancellation", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	expectStarted(t, invoker.started)
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrRequestTimeout) {
+		t.Fatal("Expected the caller's deadline", err)
+	}
+}
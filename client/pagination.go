@@ -0,0 +1,84 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidPageArgs is returned when PageArgs holds a combination of
+// arguments that a Relay connection does not accept.
+var ErrInvalidPageArgs = errors.New("Invalid pagination arguments")
+
+// PageArgs holds the standard Relay connection arguments. Zero values are
+// left out of the variables.
+type PageArgs struct {
+	First  int
+	After  string
+	Last   int
+	Before string
+}
+
+func (p PageArgs) validate() error {
+	if p.First < 0 || p.Last < 0 {
+		return fmt.Errorf("%w: first and last can not be negative", ErrInvalidPageArgs)
+	}
+	if p.First > 0 && p.Last > 0 {
+		return fmt.Errorf("%w: first and last can not both be set", ErrInvalidPageArgs)
+	}
+	return nil
+}
+
+// Merge returns a copy of variables with the page arguments added under the
+// first, after, last and before keys.
+func (p PageArgs) Merge(variables map[string]interface{}) (map[string]interface{}, error) {
+	err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]interface{}, len(variables)+2)
+	for k, v := range variables {
+		merged[k] = v
+	}
+	if p.First > 0 {
+		merged["first"] = p.First
+	}
+	if p.After != "" {
+		merged["after"] = p.After
+	}
+	if p.Last > 0 {
+		merged["last"] = p.Last
+	}
+	if p.Before != "" {
+		merged["before"] = p.Before
+	}
+	return merged, nil
+}
+
+// NextPageVariables returns the variables for the page after prev, the data
+// of a response, when paginating by hand. It reads pageInfo.hasNextPage and
+// pageInfo.endCursor from the connection at connectionPath, a dotted path as
+// taken by Get such as "patients" or "project.members", and returns a copy of
+// base with after set to the end cursor. The returned bool is false once
+// there are no more pages, in which case the variables are nil.
+func NextPageVariables(prev map[string]interface{}, connectionPath string, base map[string]interface{}) (map[string]interface{}, bool, error) {
+	pageInfo, err := Get(prev, connectionPath+".pageInfo")
+	if err != nil {
+		return nil, false, err
+	}
+	info, ok := pageInfo.(map[string]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("Path %q is a %T, not an object", connectionPath+".pageInfo", pageInfo)
+	}
+	if hasNext, _ := info["hasNextPage"].(bool); !hasNext {
+		return nil, false, nil
+	}
+	endCursor, _ := info["endCursor"].(string)
+	if endCursor == "" {
+		return nil, false, fmt.Errorf("Path %q has a next page but no endCursor", connectionPath+".pageInfo")
+	}
+	next, err := PageArgs{After: endCursor}.Merge(base)
+	if err != nil {
+		return nil, false, err
+	}
+	return next, true, nil
+}
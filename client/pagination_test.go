@@ -0,0 +1,67 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPageArgsMerge(t *testing.T) {
+	base := map[string]interface{}{"project": "some_project"}
+	merged, err := PageArgs{First: 10, After: "cursor"}.Merge(base)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if merged["first"] != 10 || merged["after"] != "cursor" || merged["project"] != "some_project" {
+		t.Fatal("Did not merge page args", merged)
+	}
+	if _, ok := merged["last"]; ok {
+		t.Fatal("Unset args should be omitted", merged)
+	}
+	if _, ok := base["first"]; ok {
+		t.Fatal("Should not modify the given variables", base)
+	}
+
+	_, err = PageArgs{First: 10, Last: 10}.Merge(base)
+	if !errors.Is(err, ErrInvalidPageArgs) {
+		t.Fatal("Expected first and last to be rejected", err)
+	}
+
+	_, err = PageArgs{First: -1}.Merge(base)
+	if !errors.Is(err, ErrInvalidPageArgs) {
+		t.Fatal("Expected negative first to be rejected", err)
+	}
+}
+
+func TestNextPageVariables(t *testing.T) {
+	base := map[string]interface{}{"project": "some_project", "first": 10}
+	prev := map[string]interface{}{
+		"project": map[string]interface{}{
+			"members": map[string]interface{}{
+				"edges":    []interface{}{},
+				"pageInfo": map[string]interface{}{"hasNextPage": true, "endCursor": "cursor-1"},
+			},
+		},
+	}
+
+	next, more, err := NextPageVariables(prev, "project.members", base)
+	if err != nil || !more {
+		t.Fatal("Expected another page", more, err)
+	}
+	if next["after"] != "cursor-1" || next["first"] != 10 || next["project"] != "some_project" {
+		t.Fatal("Did not build the next variables", next)
+	}
+	if _, ok := base["after"]; ok {
+		t.Fatal("Should not modify the base variables", base)
+	}
+
+	prev["project"].(map[string]interface{})["members"].(map[string]interface{})["pageInfo"] = map[string]interface{}{"hasNextPage": false, "endCursor": "cursor-2"}
+	next, more, err = NextPageVariables(prev, "project.members", base)
+	if err != nil || more || next != nil {
+		t.Fatal("Expected the last page", next, more, err)
+	}
+
+	_, _, err = NextPageVariables(prev, "project.owners", base)
+	if err == nil {
+		t.Fatal("Expected an error for a missing connection")
+	}
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sync"
+)
+
+var payloadBuffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// encodePayload returns the JSON encoding of a payload holding the given
+// request, encoded into a pooled buffer so only the returned copy is
+// allocated per request.
+func encodePayload(headers map[string]string, method string, path string, body []byte, isBase64Encoded bool) ([]byte, error) {
+	buf := payloadBuffers.Get().(*bytes.Buffer)
+	defer payloadBuffers.Put(buf)
+	buf.Reset()
+	err := json.NewEncoder(buf).Encode(&Payload{
+		Headers:         headers,
+		Path:            path,
+		HttpMethod:      method,
+		Body:            string(body),
+		IsBase64Encoded: isBase64Encoded,
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	return append([]byte(nil), encoded...), nil
+}
+
+// estimatingKey marks the context of a request built by EstimateRequestSize.
+type estimatingKey struct{}
+
+// EstimateRequestSize returns the size in bytes of the payload Gql would send
+// for the query without invoking the function, so callers can tell how close
+// a request is to the 6MB Lambda limit. The payload goes through the same
+// steps as for Gql, including the request id and trace headers and the
+// payload signer, except that the token provider is not called: the estimate
+// leaves out its Authorization header rather than refreshing a token.
+func (c *LambdaClient) EstimateRequestSize(path string, query string, variables map[string]interface{}) (int, error) {
+	if c.pathRewriter != nil {
+		path = c.pathRewriter(path)
+	}
+	ctx := context.WithValue(context.Background(), estimatingKey{}, true)
+	ctx, err := c.traceContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ctx, err = c.requestIDContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	data, err := c.buildGqlQuery(ctx, path, query, variables)
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Canonicalize re-encodes a JSON document with object keys sorted at every
+// level and insignificant whitespace removed, so payloads can be compared
+// byte for byte against golden files. Numbers are kept exactly as written.
+func Canonicalize(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// DecodePayload decodes the Payload of a Lambda invocation made by the
+// client, such as the Payload of the lambda.InvokeInput seen by a fake
+// Invoker.
+func DecodePayload(data []byte) (Payload, error) {
+	var decoded Payload
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return Payload{}, decodeError(ErrDecodeEnvelope, err, data)
+	}
+	return decoded, nil
+}
+
+// Header returns the value of the named header, matching the name
+// case-insensitively, or "" when the payload does not have it.
+func (p Payload) Header(name string) string {
+	value, _ := headerValue(p.Headers, name)
+	return value
+}
+
+// Policy returns the rules sent in the LifeOmic-Policy header. It does not
+// know about names set with WithHeaderNames.
+func (p Payload) Policy() (map[string]interface{}, error) {
+	var decoded policy
+	err := json.Unmarshal([]byte(p.Header(defaultHeaderNames.Policy)), &decoded)
+	if err != nil {
+		return nil, err
+	}
+	return decoded.Rules, nil
+}
+
+// DecodedBody returns the body of the payload, decoding it first when it was
+// sent base64 encoded.
+func (p Payload) DecodedBody() ([]byte, error) {
+	if p.IsBase64Encoded {
+		return base64.StdEncoding.DecodeString(p.Body)
+	}
+	return []byte(p.Body), nil
+}
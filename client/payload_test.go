@@ -0,0 +1,247 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestBuildGqlQueryIsStable(t *testing.T) {
+	client := LambdaClient{
+		account: "test-account",
+		user:    "test-user",
+		rules:   map[string]bool{"b": true, "a": false, "c": true},
+	}
+	variables := map[string]interface{}{
+		"input": map[string]interface{}{"z": 1, "y": []interface{}{map[string]interface{}{"b": 2, "a": 1}}, "x": "value"},
+		"id":    "some_id",
+	}
+	first, err := client.buildGqlQuery(context.Background(), "/some/path", MOCK_MUTATION, variables)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	for i := 0; i < 20; i++ {
+		next, err := client.buildGqlQuery(context.Background(), "/some/path", MOCK_MUTATION, variables)
+		if err != nil {
+			t.Fatal("Unexpected error", err)
+		}
+		if !bytes.Equal(first, next) {
+			t.Fatalf("Payload changed between runs:\n%s\n%s", first, next)
+		}
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	canonical, err := Canonicalize([]byte(`{ "b": { "d": 1.50, "c": [ { "f": true, "e": null } ] }, "a": "x" }`))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	expected := `{"a":"x","b":{"c":[{"e":null,"f":true}],"d":1.50}}`
+	if string(canonical) != expected {
+		t.Fatal("Did not canonicalize", string(canonical))
+	}
+
+	_, err = Canonicalize([]byte("not json"))
+	if err == nil {
+		t.Fatal("Expected an error for invalid json")
+	}
+}
+
+func TestQueryStringParametersOmittedWhenEmpty(t *testing.T) {
+	client := LambdaClient{}
+	raw, err := client.buildGqlQuery(context.Background(), "/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	var parsed map[string]interface{}
+	err = json.Unmarshal(raw, &parsed)
+	if err != nil {
+		t.Fatal("Could not parse payload as json", string(raw))
+	}
+	if _, ok := parsed["queryStringParameters"]; ok {
+		t.Fatal("queryStringParameters should be omitted", string(raw))
+	}
+}
+
+func TestEstimateRequestSize(t *testing.T) {
+	mock := MockInvoker{}
+	client := LambdaClient{
+		invoker: &mock,
+		account: "test-account",
+		user:    "test-user",
+	}
+	variables := map[string]interface{}{"var": "value"}
+
+	size, err := client.EstimateRequestSize("/some/path", MOCK_MUTATION, variables)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if mock.hasBeenCalled {
+		t.Fatal("Estimating should not invoke the function")
+	}
+	data, _ := client.buildGqlQuery(context.Background(), "/some/path", MOCK_MUTATION, variables)
+	if size != len(data) {
+		t.Fatal("Size does not match the built payload", size, len(data))
+	}
+
+	var tokenCalls int
+	client.xrayTracing = true
+	client.checkRequestIDs = true
+	client.tokenProvider = func(ctx context.Context) (string, error) {
+		tokenCalls++
+		return "token", nil
+	}
+	mock.response = &lambda.InvokeOutput{Payload: []byte(`{ "body": { "data": {} } }`)}
+	size, err = client.EstimateRequestSize("/some/path", MOCK_MUTATION, variables)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if tokenCalls != 0 {
+		t.Fatal("Estimating should not call the token provider", tokenCalls)
+	}
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, variables)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent := len(mock.payload.Payload) - len(`,"Authorization":"Bearer token"`)
+	if size != sent {
+		t.Fatal("Expected the estimate to include the request id and trace headers", size, sent)
+	}
+}
+
+func benchmarkVariables(size int) map[string]interface{} {
+	items := make([]interface{}, size)
+	for i := range items {
+		items[i] = map[string]interface{}{"id": i, "name": "some name with \"quotes\" and <html>"}
+	}
+	return map[string]interface{}{"input": map[string]interface{}{"items": items}}
+}
+
+func benchmarkGql(b *testing.B, variables map[string]interface{}) {
+	client := LambdaClient{
+		invoker: &MockInvoker{
+			response: &lambda.InvokeOutput{
+				Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+			},
+		},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		client.GqlWithContext(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, variables)
+	}
+}
+
+func BenchmarkGqlSmallPayload(b *testing.B) {
+	benchmarkGql(b, map[string]interface{}{"var": "value"})
+}
+
+func BenchmarkGqlLargePayload(b *testing.B) {
+	benchmarkGql(b, benchmarkVariables(10000))
+}
+
+func benchmarkDo(b *testing.B, body []byte) {
+	client := LambdaClient{
+		invoker: &MockInvoker{
+			response: &lambda.InvokeOutput{
+				Payload: []byte("{ \"statusCode\": 200, \"body\": \"ok\" }"),
+			},
+		},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		req, _ := http.NewRequest("POST", "some-service:deployed/resource", bytes.NewReader(body))
+		client.Do(req)
+	}
+}
+
+func BenchmarkDoSmallPayload(b *testing.B) {
+	benchmarkDo(b, []byte(`{"name":"test"}`))
+}
+
+func BenchmarkDoLargePayload(b *testing.B) {
+	body, _ := json.Marshal(benchmarkVariables(10000))
+	benchmarkDo(b, body)
+}
+
+func TestEncodePayload(t *testing.T) {
+	headers := map[string]string{"content-type": "application/json", "X-Quote": "\"<&>\""}
+	bodies := []string{
+		"",
+		`{"query":"mutation { m(s: \"a\\\\b\") }"}`,
+		"control \x00\x01\b\f\n\r\t chars",
+		"html <script>&amp;</script>",
+		"unicode é 日本    🎉",
+		"invalid \xff\xfe utf-8",
+	}
+	for _, body := range bodies {
+		encoded, err := encodePayload(headers, "POST", "/some/path", []byte(body), false)
+		if err != nil {
+			t.Fatal("Unexpected error", err)
+		}
+		expected, _ := json.Marshal(Payload{Headers: headers, HttpMethod: "POST", Path: "/some/path", Body: body})
+		var got, want Payload
+		err = json.Unmarshal(encoded, &got)
+		if err != nil {
+			t.Fatal("Did not encode valid JSON", string(encoded), err)
+		}
+		json.Unmarshal(expected, &want)
+		if got.Body != want.Body || got.Path != want.Path || got.HttpMethod != want.HttpMethod || got.Headers["X-Quote"] != want.Headers["X-Quote"] {
+			t.Fatal("Encoding does not match json.Marshal", string(encoded), string(expected))
+		}
+		if bytes.Contains(encoded, []byte("<")) {
+			t.Fatal("HTML characters should be escaped", string(encoded))
+		}
+	}
+
+	encoded, _ := encodePayload(headers, "PUT", "/some/path", []byte("Ym9keQ=="), true)
+	var got Payload
+	json.Unmarshal(encoded, &got)
+	if !got.IsBase64Encoded || got.Body != "Ym9keQ==" {
+		t.Fatal("Did not encode base64 flag", string(encoded))
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "statusCode": 200, "body": "{}" }`),
+		},
+	}
+	client := LambdaClient{
+		invoker:      &mock,
+		account:      "test-account",
+		rules:        map[string]bool{"readData": true},
+		base64Bodies: true,
+	}
+
+	req, _ := http.NewRequest("PUT", "some-service:deployed/resource", bytes.NewBufferString("resource body"))
+	_, err := client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent, err := DecodePayload(mock.payload.Payload)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if sent.HttpMethod != "PUT" || sent.Path != "/resource" || sent.Header("lifeomic-account") != "test-account" {
+		t.Fatal("Did not decode the payload", sent)
+	}
+	rules, err := sent.Policy()
+	if err != nil || rules["readData"] != true {
+		t.Fatal("Did not decode the policy", rules, err)
+	}
+	body, err := sent.DecodedBody()
+	if err != nil || string(body) != "resource body" {
+		t.Fatal("Did not decode the body", string(body), err)
+	}
+
+	_, err = DecodePayload([]byte("not json"))
+	if !errors.Is(err, ErrDecodeEnvelope) {
+		t.Fatal("Expected a decode error", err)
+	}
+}
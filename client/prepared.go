@@ -0,0 +1,53 @@
+package client
+
+import "context"
+
+// PreparedQuery is a GraphQL query bound to a function and path, ready to be
+// executed many times with different variables and contexts.
+type PreparedQuery struct {
+	client        *LambdaClient
+	functionName  string
+	path          string
+	query         string
+	operationName string
+	extensions    map[string]interface{}
+}
+
+// Prepare parses uri and checks query once, so the returned PreparedQuery can
+// be executed repeatedly without repeating that work on every call.
+func (c *LambdaClient) Prepare(uri string, query string) (*PreparedQuery, error) {
+	functionName, path, err := c.resolveUri(uri)
+	if err != nil {
+		return nil, err
+	}
+	err = c.checkQueryDepth(query)
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedQuery{
+		client:       c,
+		functionName: *functionName,
+		path:         *path,
+		query:        query,
+	}, nil
+}
+
+// request returns the GraphQL request sending the query with variables.
+func (q *PreparedQuery) request(variables map[string]interface{}) GraphQLRequest {
+	return GraphQLRequest{
+		Query:         q.query,
+		Variables:     variables,
+		OperationName: q.operationName,
+		Extensions:    q.extensions,
+	}
+}
+
+// Execute sends the query with the given variables and returns its data
+// like GqlWithContext.
+func (q *PreparedQuery) Execute(ctx context.Context, variables map[string]interface{}) (*map[string]interface{}, error) {
+	resp, err := q.client.gqlResponse(ctx, q, variables)
+	if resp == nil {
+		return nil, err
+	}
+	return &resp.Data, err
+}
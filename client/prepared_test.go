@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestPreparedQuery(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	_, err := client.Prepare("some_lambda:status.invalid_path", MOCK_MUTATION)
+	if err == nil {
+		t.Fatal("Expected an invalid URL error")
+	}
+
+	prepared, err := client.Prepare("some_lambda:status/some/path", MOCK_MUTATION)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	res, err := prepared.Execute(context.Background(), map[string]interface{}{"var": "value"})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if *mock.payload.FunctionName != "some_lambda:status" {
+		t.Fatal("Did not use correct function name", *mock.payload.FunctionName)
+	}
+	if !(*res)["result"].(bool) {
+		t.Fatal("Did not return data", *res)
+	}
+}
+
+func BenchmarkGql(b *testing.B) {
+	client := LambdaClient{
+		invoker: &MockInvoker{
+			response: &lambda.InvokeOutput{
+				Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+			},
+		},
+	}
+	variables := map[string]interface{}{"var": "value"}
+	for i := 0; i < b.N; i++ {
+		client.GqlWithContext(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, variables)
+	}
+}
+
+func BenchmarkPreparedQuery(b *testing.B) {
+	client := LambdaClient{
+		invoker: &MockInvoker{
+			response: &lambda.InvokeOutput{
+				Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+			},
+		},
+	}
+	prepared, _ := client.Prepare("some_lambda:status/some/path", MOCK_MUTATION)
+	variables := map[string]interface{}{"var": "value"}
+	for i := 0; i < b.N; i++ {
+		prepared.Execute(context.Background(), variables)
+	}
+}
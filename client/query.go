@@ -0,0 +1,591 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	punctuatorToken tokenKind = iota
+	nameToken
+	numberToken
+	stringToken
+)
+
+type token struct {
+	kind   tokenKind
+	value  string
+	line   int
+	column int
+}
+
+// QuerySyntaxError reports a problem with a GraphQL document found before
+// it is sent.
+type QuerySyntaxError struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("GraphQL syntax error at line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// lex splits a GraphQL document into tokens, dropping whitespace, commas and
+// comments.
+func lex(query string) ([]token, error) {
+	var tokens []token
+	line, lineStart := 1, 0
+	for i := 0; i < len(query); {
+		c := query[i]
+		column := i - lineStart + 1
+		syntaxError := func(message string) error {
+			return &QuerySyntaxError{Message: message, Line: line, Column: column}
+		}
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "..."):
+			tokens = append(tokens, token{punctuatorToken, "...", line, column})
+			i += 3
+		case strings.ContainsRune("!$&():=@[]{|}", rune(c)):
+			tokens = append(tokens, token{punctuatorToken, string(c), line, column})
+			i++
+		case isNameStart(c):
+			start := i
+			for i < len(query) && (isNameStart(query[i]) || isDigit(query[i])) {
+				i++
+			}
+			tokens = append(tokens, token{nameToken, query[start:i], line, column})
+		case isDigit(c) || c == '-':
+			start := i
+			i++
+			for i < len(query) && (isDigit(query[i]) || strings.ContainsRune(".eE+-", rune(query[i]))) {
+				i++
+			}
+			if query[start:i] == "-" {
+				return nil, syntaxError("Invalid number")
+			}
+			tokens = append(tokens, token{numberToken, query[start:i], line, column})
+		case strings.HasPrefix(query[i:], `"""`):
+			// Block strings end at the first """ that is not escaped as \""".
+			end := i + 3
+			for end < len(query) && !strings.HasPrefix(query[end:], `"""`) {
+				if strings.HasPrefix(query[end:], `\"""`) {
+					end += 4
+					continue
+				}
+				if query[end] == '\n' {
+					line, lineStart = line+1, end+1
+				}
+				end++
+			}
+			if end >= len(query) {
+				return nil, syntaxError("Unterminated block string")
+			}
+			tokens = append(tokens, token{stringToken, query[i+3 : end], line, column})
+			i = end + 3
+		case c == '"':
+			start := i
+			i++
+			for i < len(query) && query[i] != '"' {
+				if query[i] == '\n' {
+					return nil, syntaxError("Unterminated string")
+				}
+				if query[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(query) {
+				return nil, syntaxError("Unterminated string")
+			}
+			i++
+			tokens = append(tokens, token{stringToken, query[start+1 : i-1], line, column})
+		default:
+			return nil, syntaxError(fmt.Sprintf("Unexpected character %q", c))
+		}
+	}
+	return tokens, nil
+}
+
+type definitionDepth struct {
+	depth   int
+	spreads []fragmentSpread
+}
+
+type fragmentSpread struct {
+	name  string
+	depth int
+}
+
+// queryDepth returns the deepest level of field nesting in a GraphQL
+// document, following fragment spreads. Inline fragments and argument
+// object values do not add to the depth.
+func queryDepth(query string) (int, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return 0, err
+	}
+
+	operations := []*definitionDepth{}
+	fragments := map[string]*definitionDepth{}
+	var current *definitionDepth
+	// counted records for each open brace whether it started a new level
+	var counted []bool
+	depth, parens := 0, 0
+	fragmentName, inlineFragment := "", false
+	for i, tok := range tokens {
+		if tok.kind != punctuatorToken {
+			// Only the fragment keyword of a definition starts a fragment,
+			// not a variable, argument or field of the same name.
+			isKeyword := len(counted) == 0 && parens == 0 && (i == 0 || tokens[i-1].value != "$")
+			if tok.kind == nameToken && tok.value == "fragment" && isKeyword && i+1 < len(tokens) && tokens[i+1].kind == nameToken {
+				fragmentName = tokens[i+1].value
+			}
+			continue
+		}
+		switch tok.value {
+		case "(":
+			parens++
+		case ")":
+			parens--
+		case "...":
+			if current == nil {
+				continue
+			}
+			if i+1 < len(tokens) && tokens[i+1].kind == nameToken && tokens[i+1].value != "on" {
+				current.spreads = append(current.spreads, fragmentSpread{tokens[i+1].value, depth})
+			} else {
+				inlineFragment = true
+			}
+		case "{":
+			if parens > 0 {
+				continue
+			}
+			if len(counted) == 0 {
+				current = &definitionDepth{}
+				if fragmentName != "" {
+					fragments[fragmentName] = current
+				} else {
+					operations = append(operations, current)
+				}
+				fragmentName = ""
+			}
+			counted = append(counted, !inlineFragment)
+			if !inlineFragment {
+				depth++
+				if depth > current.depth {
+					current.depth = depth
+				}
+			}
+			inlineFragment = false
+		case "}":
+			if parens > 0 || len(counted) == 0 {
+				continue
+			}
+			if counted[len(counted)-1] {
+				depth--
+			}
+			counted = counted[:len(counted)-1]
+		}
+	}
+	// Unbalanced parentheses would hide the braces after them from the
+	// count, so such documents are rejected rather than measured.
+	if parens != 0 || len(counted) != 0 {
+		last := tokens[len(tokens)-1]
+		return 0, &QuerySyntaxError{Message: "Unbalanced parentheses or braces", Line: last.line, Column: last.column}
+	}
+
+	resolved := map[string]int{}
+	var resolve func(def *definitionDepth, seen map[string]bool) (int, error)
+	resolve = func(def *definitionDepth, seen map[string]bool) (int, error) {
+		deepest := def.depth
+		for _, spread := range def.spreads {
+			fragment, ok := fragments[spread.name]
+			if !ok {
+				continue
+			}
+			if seen[spread.name] {
+				return 0, fmt.Errorf("Fragment %s spreads itself", spread.name)
+			}
+			fragmentDepth, ok := resolved[spread.name]
+			if !ok {
+				seen[spread.name] = true
+				fragmentDepth, err = resolve(fragment, seen)
+				if err != nil {
+					return 0, err
+				}
+				delete(seen, spread.name)
+				resolved[spread.name] = fragmentDepth
+			}
+			if spread.depth-1+fragmentDepth > deepest {
+				deepest = spread.depth - 1 + fragmentDepth
+			}
+		}
+		return deepest, nil
+	}
+
+	deepest := 0
+	for _, operation := range operations {
+		operationDepth, err := resolve(operation, map[string]bool{})
+		if err != nil {
+			return 0, err
+		}
+		if operationDepth > deepest {
+			deepest = operationDepth
+		}
+	}
+	return deepest, nil
+}
+
+func (c *LambdaClient) checkQueryDepth(query string) error {
+	if c.maxQueryDepth <= 0 {
+		return nil
+	}
+	depth, err := queryDepth(query)
+	if err != nil {
+		return err
+	}
+	if depth > c.maxQueryDepth {
+		return fmt.Errorf("%w: depth %d exceeds the limit of %d", ErrQueryTooComplex, depth, c.maxQueryDepth)
+	}
+	return nil
+}
+
+// ValidateQuery checks that query is a syntactically valid GraphQL document,
+// returning a *QuerySyntaxError with the line and column of the first
+// problem. It does not check the query against a schema.
+func ValidateQuery(query string) error {
+	tokens, err := lex(query)
+	if err != nil {
+		return err
+	}
+	p := &parser{tokens: tokens}
+	if p.done() {
+		return p.unexpected("a definition")
+	}
+	for !p.done() {
+		err = p.parseDefinition()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parser is a recursive descent parser for executable GraphQL documents that
+// only checks their structure.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	if p.done() {
+		return false
+	}
+	tok := p.tokens[p.pos]
+	return tok.kind == kind && (value == "" || tok.value == value)
+}
+
+// unexpected returns an error for the current token, or for the end of the
+// document when all tokens have been consumed.
+func (p *parser) unexpected(expected string) error {
+	if p.done() {
+		line, column := 1, 1
+		if len(p.tokens) > 0 {
+			last := p.tokens[len(p.tokens)-1]
+			line, column = last.line, last.column+len(last.value)
+		}
+		return &QuerySyntaxError{Message: fmt.Sprintf("Expected %s, found end of document", expected), Line: line, Column: column}
+	}
+	tok := p.tokens[p.pos]
+	return &QuerySyntaxError{Message: fmt.Sprintf("Expected %s, found %q", expected, tok.value), Line: tok.line, Column: tok.column}
+}
+
+func (p *parser) expect(kind tokenKind, value string, expected string) error {
+	if !p.peek(kind, value) {
+		return p.unexpected(expected)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) expectPunctuator(value string) error {
+	return p.expect(punctuatorToken, value, fmt.Sprintf("%q", value))
+}
+
+func (p *parser) parseDefinition() error {
+	if p.peek(punctuatorToken, "{") {
+		return p.parseSelectionSet()
+	}
+	if p.peek(nameToken, "fragment") {
+		p.pos++
+		if p.peek(nameToken, "on") {
+			return p.unexpected("a fragment name")
+		}
+		err := p.expect(nameToken, "", "a fragment name")
+		if err != nil {
+			return err
+		}
+		err = p.parseTypeCondition()
+		if err != nil {
+			return err
+		}
+		err = p.parseDirectives()
+		if err != nil {
+			return err
+		}
+		return p.parseSelectionSet()
+	}
+	if !p.peek(nameToken, "query") && !p.peek(nameToken, "mutation") && !p.peek(nameToken, "subscription") {
+		return p.unexpected("a query, mutation, subscription or fragment")
+	}
+	p.pos++
+	if p.peek(nameToken, "") {
+		p.pos++
+	}
+	if p.peek(punctuatorToken, "(") {
+		err := p.parseVariableDefinitions()
+		if err != nil {
+			return err
+		}
+	}
+	err := p.parseDirectives()
+	if err != nil {
+		return err
+	}
+	return p.parseSelectionSet()
+}
+
+func (p *parser) parseVariableDefinitions() error {
+	p.pos++
+	for {
+		err := p.expectPunctuator("$")
+		if err != nil {
+			return err
+		}
+		err = p.expect(nameToken, "", "a variable name")
+		if err != nil {
+			return err
+		}
+		err = p.expectPunctuator(":")
+		if err != nil {
+			return err
+		}
+		err = p.parseType()
+		if err != nil {
+			return err
+		}
+		if p.peek(punctuatorToken, "=") {
+			p.pos++
+			err = p.parseValue()
+			if err != nil {
+				return err
+			}
+		}
+		err = p.parseDirectives()
+		if err != nil {
+			return err
+		}
+		if p.peek(punctuatorToken, ")") {
+			p.pos++
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseType() error {
+	if p.peek(punctuatorToken, "[") {
+		p.pos++
+		err := p.parseType()
+		if err != nil {
+			return err
+		}
+		err = p.expectPunctuator("]")
+		if err != nil {
+			return err
+		}
+	} else {
+		err := p.expect(nameToken, "", "a type")
+		if err != nil {
+			return err
+		}
+	}
+	if p.peek(punctuatorToken, "!") {
+		p.pos++
+	}
+	return nil
+}
+
+func (p *parser) parseTypeCondition() error {
+	err := p.expect(nameToken, "on", `"on"`)
+	if err != nil {
+		return err
+	}
+	return p.expect(nameToken, "", "a type name")
+}
+
+func (p *parser) parseDirectives() error {
+	for p.peek(punctuatorToken, "@") {
+		p.pos++
+		err := p.expect(nameToken, "", "a directive name")
+		if err != nil {
+			return err
+		}
+		if p.peek(punctuatorToken, "(") {
+			err = p.parseArguments()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseSelectionSet() error {
+	err := p.expectPunctuator("{")
+	if err != nil {
+		return err
+	}
+	for {
+		err = p.parseSelection()
+		if err != nil {
+			return err
+		}
+		if p.peek(punctuatorToken, "}") {
+			p.pos++
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseSelection() error {
+	if p.peek(punctuatorToken, "...") {
+		p.pos++
+		if p.peek(nameToken, "") && !p.peek(nameToken, "on") {
+			p.pos++
+			return p.parseDirectives()
+		}
+		if p.peek(nameToken, "on") {
+			err := p.parseTypeCondition()
+			if err != nil {
+				return err
+			}
+		}
+		err := p.parseDirectives()
+		if err != nil {
+			return err
+		}
+		return p.parseSelectionSet()
+	}
+	err := p.expect(nameToken, "", "a field")
+	if err != nil {
+		return err
+	}
+	if p.peek(punctuatorToken, ":") {
+		p.pos++
+		err = p.expect(nameToken, "", "a field")
+		if err != nil {
+			return err
+		}
+	}
+	if p.peek(punctuatorToken, "(") {
+		err = p.parseArguments()
+		if err != nil {
+			return err
+		}
+	}
+	err = p.parseDirectives()
+	if err != nil {
+		return err
+	}
+	if p.peek(punctuatorToken, "{") {
+		return p.parseSelectionSet()
+	}
+	return nil
+}
+
+func (p *parser) parseArguments() error {
+	p.pos++
+	for {
+		err := p.expect(nameToken, "", "an argument name")
+		if err != nil {
+			return err
+		}
+		err = p.expectPunctuator(":")
+		if err != nil {
+			return err
+		}
+		err = p.parseValue()
+		if err != nil {
+			return err
+		}
+		if p.peek(punctuatorToken, ")") {
+			p.pos++
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseValue() error {
+	switch {
+	case p.peek(punctuatorToken, "$"):
+		p.pos++
+		return p.expect(nameToken, "", "a variable name")
+	case p.peek(nameToken, ""), p.peek(numberToken, ""), p.peek(stringToken, ""):
+		p.pos++
+		return nil
+	case p.peek(punctuatorToken, "["):
+		p.pos++
+		for !p.peek(punctuatorToken, "]") {
+			err := p.parseValue()
+			if err != nil {
+				return err
+			}
+		}
+		p.pos++
+		return nil
+	case p.peek(punctuatorToken, "{"):
+		p.pos++
+		for !p.peek(punctuatorToken, "}") {
+			err := p.expect(nameToken, "", "a field name")
+			if err != nil {
+				return err
+			}
+			err = p.expectPunctuator(":")
+			if err != nil {
+				return err
+			}
+			err = p.parseValue()
+			if err != nil {
+				return err
+			}
+		}
+		p.pos++
+		return nil
+	}
+	return p.unexpected("a value")
+}
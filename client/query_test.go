@@ -0,0 +1,125 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestQueryDepth(t *testing.T) {
+	cases := []struct {
+		query string
+		depth int
+	}{
+		{"{ app }", 1},
+		{GET_APP_STORE_LISTING, 2},
+		{GET_PUBLISHED_APP_TILE_MODULE, 3},
+		{`query Q($input: In = { a: { b: 1 } }) { app(filter: { nested: { deep: true } }) { name } }`, 2},
+		{`query { a { ...F } } fragment F on A { b { c { d } } }`, 4},
+		{`query { a { ... on B @include(if: true) { c } } }`, 2},
+		{`# comment with { braces
+		  query { a(s: "}") { b } }`, 2},
+		{`query { a # comment with "quote and } brace
+		  { b { c } } }`, 3},
+		{`query { a(s: """block { string with \""" and { braces""") { b { c } } }`, 3},
+		{"query { a(s: \"\"\"multi\nline { \"\"\") { b } }", 2},
+		{`query Q($fragment: Int) { a { b { c } } }`, 3},
+		{`query Q @skip(fragment: true) { a { b } }`, 2},
+		{`query { fragment { b { c } } }`, 3},
+	}
+	for _, c := range cases {
+		depth, err := queryDepth(c.query)
+		if err != nil {
+			t.Fatal("Unexpected error", err)
+		}
+		if depth != c.depth {
+			t.Fatalf("Expected depth %d, got %d for %s", c.depth, depth, c.query)
+		}
+	}
+
+	_, err := queryDepth(`query { a { ...F } } fragment F on A { b { ...F } }`)
+	if err == nil {
+		t.Fatal("Expected an error for cyclic fragments")
+	}
+
+	for _, query := range []string{`query { a(( { b { c } } }`, `query { a { b }`, `query { a(s: """unterminated) { b } }`} {
+		_, err = queryDepth(query)
+		var syntaxErr *QuerySyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatal("Expected a syntax error", query, err)
+		}
+	}
+}
+
+func TestMaxQueryDepth(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := LambdaClient{
+		invoker:       &mock,
+		maxQueryDepth: 3,
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", `{ a { b { c } } }`, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	mock.hasBeenCalled = false
+	_, err = client.Gql("some_lambda:status/some/path", `{ a { b { c { d { e } } } } }`, map[string]interface{}{})
+	if !errors.Is(err, ErrQueryTooComplex) {
+		t.Fatal("Expected query to be rejected", err)
+	}
+	if mock.hasBeenCalled {
+		t.Fatal("Rejected queries should not be sent")
+	}
+}
+
+func TestValidateQuery(t *testing.T) {
+	valid := []string{
+		"{ app }",
+		MOCK_MUTATION,
+		MOCK_LIST_QUERY,
+		GET_APP_STORE_LISTING,
+		GET_PUBLISHED_APP_TILE_MODULE,
+		GET_DATASET,
+		LIST_DATASETS,
+		`query Q($input: [In!]! = [{ a: { b: 1 } }] @dir) { app(filter: { nested: { deep: true } }, e: ENUM, n: null) { name } }`,
+		`query { a { ...F ... on B @include(if: $x) { c } ... @skip(if: false) { d } } } fragment F on A { alias: b { c } }`,
+		`subscription OnEvent { event { id } }`,
+	}
+	for _, query := range valid {
+		err := ValidateQuery(query)
+		if err != nil {
+			t.Fatal("Unexpected error for", query, err)
+		}
+	}
+
+	invalid := []struct {
+		query  string
+		line   int
+		column int
+	}{
+		{"", 1, 1},
+		{"{ app ", 1, 6},
+		{"query {\n  app(id: ) { name }\n}", 2, 11},
+		{"query { app { } }", 1, 15},
+		{"fragment on A { b }", 1, 10},
+		{"mutation M($id String) { a }", 1, 16},
+		{"{ a } garbage", 1, 7},
+		{"{ a(s: \"unterminated) }", 1, 8},
+	}
+	for _, c := range invalid {
+		err := ValidateQuery(c.query)
+		var syntaxErr *QuerySyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatal("Expected a syntax error for", c.query, err)
+		}
+		if syntaxErr.Line != c.line || syntaxErr.Column != c.column {
+			t.Fatal("Unexpected error position for", c.query, err)
+		}
+	}
+}
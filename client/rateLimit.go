@@ -0,0 +1,81 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitInfo is the rate limit state a gateway reported in the headers of
+// a response.
+type RateLimitInfo struct {
+	// Limit is the number of requests allowed in the current window.
+	Limit int
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+	// Reset is when the current window ends, or the zero time when the
+	// response did not say.
+	Reset time.Time
+}
+
+// Gateways name their rate limit headers after one of these prefixes.
+var rateLimitPrefixes = []string{"X-RateLimit-", "X-Rate-Limit-", "RateLimit-"}
+
+// Reset values below this are a number of seconds from now rather than a
+// Unix time.
+const minRateLimitResetEpoch = 1000000000
+
+// parseRateLimit returns the rate limit state in headers, or nil when they
+// hold none. Headers may be named X-RateLimit-*, X-Rate-Limit-* or, as in
+// the IETF draft, RateLimit-*.
+func parseRateLimit(headers map[string]string, now time.Time) *RateLimitInfo {
+	for _, prefix := range rateLimitPrefixes {
+		limit, hasLimit := rateLimitValue(headers, prefix+"Limit")
+		remaining, hasRemaining := rateLimitValue(headers, prefix+"Remaining")
+		reset, hasReset := rateLimitValue(headers, prefix+"Reset")
+		if !hasLimit && !hasRemaining && !hasReset {
+			continue
+		}
+		info := &RateLimitInfo{Limit: limit, Remaining: remaining}
+		if hasReset {
+			if reset >= minRateLimitResetEpoch {
+				info.Reset = time.Unix(int64(reset), 0)
+			} else {
+				info.Reset = now.Add(time.Duration(reset) * time.Second)
+			}
+		}
+		return info
+	}
+	return nil
+}
+
+// rateLimitValue returns the leading number of a header, ignoring policy
+// parameters such as the "100, 100;w=60" form of RateLimit-Limit.
+func rateLimitValue(headers map[string]string, name string) (int, bool) {
+	value, ok := headerValue(headers, name)
+	if !ok {
+		return 0, false
+	}
+	if end := strings.IndexAny(value, ",;"); end != -1 {
+		value = value[:end]
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// RateLimit returns the rate limit state in the headers of a response
+// returned by Do, or nil when it has none. Reset times sent as a number of
+// seconds are counted from the client's clock.
+func (c *LambdaClient) RateLimit(resp *http.Response) *RateLimitInfo {
+	headers := make(map[string]string, len(resp.Header))
+	for k, v := range resp.Header {
+		if len(v) > 0 {
+			headers[k] = v[0]
+		}
+	}
+	return parseRateLimit(headers, c.now())
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cases := []struct {
+		headers  map[string]string
+		expected *RateLimitInfo
+	}{
+		{
+			map[string]string{"X-RateLimit-Limit": "100", "X-RateLimit-Remaining": "42", "X-RateLimit-Reset": "1700000060"},
+			&RateLimitInfo{Limit: 100, Remaining: 42, Reset: time.Unix(1700000060, 0)},
+		},
+		{
+			map[string]string{"x-rate-limit-limit": "50", "x-rate-limit-remaining": "0", "x-rate-limit-reset": "30"},
+			&RateLimitInfo{Limit: 50, Remaining: 0, Reset: now.Add(30 * time.Second)},
+		},
+		{
+			map[string]string{"RateLimit-Limit": "10, 10;w=1", "RateLimit-Remaining": "9"},
+			&RateLimitInfo{Limit: 10, Remaining: 9},
+		},
+		{
+			map[string]string{"content-type": "application/json"},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		info := parseRateLimit(c.headers, now)
+		if (info == nil) != (c.expected == nil) || (info != nil && (info.Limit != c.expected.Limit || info.Remaining != c.expected.Remaining || !info.Reset.Equal(c.expected.Reset))) {
+			t.Fatal("Unexpected rate limit info", c.headers, info)
+		}
+	}
+}
+
+func TestRateLimitInResponses(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "statusCode": 200, "headers": { "X-RateLimit-Limit": "100", "X-RateLimit-Remaining": "99", "X-RateLimit-Reset": "60" }, "body": "{ \"data\": {} }" }`),
+		},
+	}
+	now := time.Unix(1700000000, 0)
+	client := LambdaClient{
+		invoker: &mock,
+		clock:   func() time.Time { return now },
+	}
+
+	res, err := client.GqlWithMetadata(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, nil)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if res.RateLimit == nil || res.RateLimit.Remaining != 99 || !res.RateLimit.Reset.Equal(now.Add(time.Minute)) {
+		t.Fatal("Expected the rate limit of the response", res.RateLimit)
+	}
+
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", bytes.NewReader(nil))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if info := client.RateLimit(resp); info == nil || info.Limit != 100 || info.Remaining != 99 || !info.Reset.Equal(now.Add(time.Minute)) {
+		t.Fatal("Expected the rate limit of the Do response", info)
+	}
+}
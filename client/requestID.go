@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context whose requests are tagged with the
+// given id in the X-Request-Id header.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// requestIDContext makes sure ctx carries a request id when an id generator
+// is set or request id checks are enabled, generating one if the caller did
+// not set it.
+func (c *LambdaClient) requestIDContext(ctx context.Context) (context.Context, error) {
+	if requestIDFromContext(ctx) != "" || (c.idGenerator == nil && !c.checkRequestIDs) {
+		return ctx, nil
+	}
+	if c.idGenerator != nil {
+		return ContextWithRequestID(ctx, c.idGenerator()), nil
+	}
+	requestID, err := c.randomUUID()
+	if err != nil {
+		return nil, err
+	}
+	return ContextWithRequestID(ctx, requestID), nil
+}
+
+// checkRequestID logs a warning when the response echoes a request id other
+// than the one sent with the request. Responses without the header are not
+// checked since functions are not required to echo it.
+func (c *LambdaClient) checkRequestID(ctx context.Context, payload *responsePayload) {
+	if !c.checkRequestIDs {
+		return
+	}
+	echoed, ok := headerValue(payload.Headers, requestIDHeader)
+	if sent := requestIDFromContext(ctx); ok && echoed != sent {
+		c.logf("Response request id %q does not match request id %q", echoed, sent)
+	}
+}
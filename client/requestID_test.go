@@ -0,0 +1,117 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestIDCheck(t *testing.T) {
+	var logs bytes.Buffer
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "headers": { "x-request-id": "some_id" }, "body": "{ \"data\": { \"result\": true } }" }`),
+		},
+	}
+	client := LambdaClient{
+		invoker:         &mock,
+		checkRequestIDs: true,
+		logger:          log.New(&logs, "", 0),
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	var sent Payload
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if !uuidPattern.MatchString(sent.Headers[requestIDHeader]) {
+		t.Fatal("Did not generate a UUID request id", sent.Headers)
+	}
+	if !strings.Contains(logs.String(), "does not match") {
+		t.Fatal("Expected a mismatch warning", logs.String())
+	}
+
+	logs.Reset()
+	ctx := ContextWithRequestID(context.Background(), "some_id")
+	_, err = client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if sent.Headers[requestIDHeader] != "some_id" {
+		t.Fatal("Did not send the context request id", sent.Headers)
+	}
+	if logs.Len() != 0 {
+		t.Fatal("Unexpected warning for a matching id", logs.String())
+	}
+
+	client.checkRequestIDs = false
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent = Payload{}
+	json.Unmarshal(mock.payload.Payload, &sent)
+	if _, ok := sent.Headers[requestIDHeader]; ok {
+		t.Fatal("Request ids should not be generated without checks or a generator", sent.Headers)
+	}
+	if logs.Len() != 0 {
+		t.Fatal("Unexpected warning with checks disabled", logs.String())
+	}
+}
+
+func TestIDGenerator(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": "{ \"data\": { \"result\": true } }" }`),
+		},
+	}
+	client := LambdaClient{
+		invoker:         &mock,
+		checkRequestIDs: true,
+	}
+	generated := 0
+	WithIDGenerator(func() string {
+		generated++
+		return fmt.Sprintf("ksuid-%d", generated)
+	})(&client)
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent, _ := DecodePayload(mock.payload.Payload)
+	if sent.Header(requestIDHeader) != "ksuid-1" {
+		t.Fatal("Did not use the id generator", sent.Headers)
+	}
+
+	ctx := ContextWithRequestID(context.Background(), "some_id")
+	_, err = client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent, _ = DecodePayload(mock.payload.Payload)
+	if sent.Header(requestIDHeader) != "some_id" || generated != 1 {
+		t.Fatal("Should not generate an id when the context has one", sent.Headers, generated)
+	}
+
+	client.checkRequestIDs = false
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent, _ = DecodePayload(mock.payload.Payload)
+	if sent.Header(requestIDHeader) != "ksuid-2" {
+		t.Fatal("Expected the generator to be used without the request id check", sent.Headers)
+	}
+}
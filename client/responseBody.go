@@ -0,0 +1,93 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// UnmarshalJSON accepts a body that is either a JSON string, as API Gateway
+// style handlers return it, or a JSON object or array embedded as is, which
+// some integrations return instead. An embedded body is kept as its raw JSON
+// text so both forms decode the same way from there.
+func (p *responsePayload) UnmarshalJSON(data []byte) error {
+	type plain responsePayload
+	var raw struct {
+		plain
+		Body json.RawMessage `json:"body"`
+	}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	*p = responsePayload(raw.plain)
+	body := bytes.TrimSpace(raw.Body)
+	if len(body) == 0 || bytes.Equal(body, []byte("null")) {
+		return nil
+	}
+	if body[0] != '"' {
+		p.Body = string(body)
+		return nil
+	}
+	return json.Unmarshal(body, &p.Body)
+}
+
+// headerValue looks up a response header ignoring case, since functions are
+// free to use any casing for their header names.
+func headerValue(headers map[string]string, name string) (string, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// decodeBody returns the raw bytes of the response body, decompressing gzip
+// encoded bodies when response decompression is enabled. A compressed body
+// is binary, so it is base64 decoded first when the function flagged it as
+// such; other bodies are returned exactly as the function sent them. The
+// returned bool reports whether the body was decompressed.
+func (c *LambdaClient) decodeBody(payload *responsePayload) ([]byte, bool, error) {
+	body := []byte(payload.Body)
+	encoding, _ := headerValue(payload.Headers, "Content-Encoding")
+	if !c.decompressResponses || !strings.EqualFold(encoding, "gzip") {
+		return body, false, nil
+	}
+	if payload.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(payload.Body)
+		if err != nil {
+			return nil, false, decodeError(ErrDecodeBody, err, body)
+		}
+		body = decoded
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, false, decodeError(ErrDecodeBody, err, body)
+	}
+	defer reader.Close()
+	decompressed, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, false, decodeError(ErrDecodeBody, err, body)
+	}
+	return decompressed, true, nil
+}
+
+// unwrapEnvelope returns the value under key of the JSON object raw, for
+// gateways that nest the GraphQL response inside an envelope of their own.
+func unwrapEnvelope(raw []byte, key string) ([]byte, error) {
+	var envelope map[string]json.RawMessage
+	err := json.Unmarshal(raw, &envelope)
+	if err != nil {
+		return nil, decodeError(ErrDecodeBody, err, raw)
+	}
+	inner, ok := envelope[key]
+	if !ok {
+		return nil, decodeError(ErrDecodeBody, fmt.Errorf("missing envelope key %q", key), raw)
+	}
+	return inner, nil
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func gzipPayload(t *testing.T, body string) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+	payload, _ := json.Marshal(responsePayload{
+		Body:            base64.StdEncoding.EncodeToString(buf.Bytes()),
+		StatusCode:      200,
+		Headers:         map[string]string{"content-encoding": "gzip"},
+		IsBase64Encoded: true,
+	})
+	return payload
+}
+
+func TestAcceptEncodingOnlyWhenDecompressing(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}"),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if encoding, ok := payloadHeaders(t, &mock)["Accept-Encoding"]; ok {
+		t.Fatal("Should not advertise gzip by default", encoding)
+	}
+
+	client.decompressResponses = true
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if encoding := payloadHeaders(t, &mock)["Accept-Encoding"]; encoding != "gzip" {
+		t.Fatal("Expected gzip to be advertised", encoding)
+	}
+}
+
+func TestGqlDecompressesResponse(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: gzipPayload(t, "{ \"data\": { \"result\": true } }"),
+		},
+	}
+	client := LambdaClient{
+		invoker:             &mock,
+		decompressResponses: true,
+	}
+
+	res, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if !(*res)["result"].(bool) {
+		t.Fatal("Did not return data", *res)
+	}
+}
+
+func TestDoDecompressesResponse(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: gzipPayload(t, "compressed body"),
+		},
+	}
+	client := LambdaClient{
+		invoker:             &mock,
+		decompressResponses: true,
+	}
+
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "compressed body" {
+		t.Fatal("Did not decompress body", string(body))
+	}
+	if !resp.Uncompressed {
+		t.Fatal("Response should be marked as uncompressed")
+	}
+	if _, ok := resp.Header["content-encoding"]; ok {
+		t.Fatal("Content-Encoding should be removed after decompression", resp.Header)
+	}
+
+	mock.response.Payload = []byte(`{ "statusCode": 200, "body": "Ym9keQ==", "isBase64Encoded": true }`)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	if string(body) != "Ym9keQ==" {
+		t.Fatal("Uncompressed bodies should be returned as sent", string(body))
+	}
+}
+
+func TestGqlBodyShapes(t *testing.T) {
+	payloads := map[string]string{
+		"string": `{ "statusCode": 200, "body": "{ \"data\": { \"result\": true } }" }`,
+		"object": `{ "statusCode": 200, "body": { "data": { "result": true } } }`,
+	}
+	for shape, payload := range payloads {
+		mock := MockInvoker{
+			response: &lambda.InvokeOutput{
+				Payload: []byte(payload),
+			},
+		}
+		client := LambdaClient{
+			invoker: &mock,
+		}
+
+		res, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+		if err != nil {
+			t.Fatal("Unexpected error for", shape, "body", err)
+		}
+		if !(*res)["result"].(bool) {
+			t.Fatal("Did not return data for", shape, "body", *res)
+		}
+	}
+
+	var parsed responsePayload
+	err := json.Unmarshal([]byte(`{ "statusCode": 204, "body": null }`), &parsed)
+	if err != nil || parsed.Body != "" || parsed.StatusCode != 204 {
+		t.Fatal("Did not handle a null body", parsed, err)
+	}
+}
+
+func TestResponseTransform(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: gzipPayload(t, `{ "result": { "data": { "result": true } } }`),
+		},
+	}
+	client := LambdaClient{
+		invoker:             &mock,
+		decompressResponses: true,
+		responseTransform: func(raw []byte) ([]byte, error) {
+			var envelope struct {
+				Result json.RawMessage
+			}
+			err := json.Unmarshal(raw, &envelope)
+			return envelope.Result, err
+		},
+	}
+
+	res, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if !(*res)["result"].(bool) {
+		t.Fatal("Did not decode transformed body", *res)
+	}
+
+	client.responseTransform = func(raw []byte) ([]byte, error) {
+		return nil, errors.New("transform failed")
+	}
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || err.Error() != "transform failed" {
+		t.Fatal("Expected transform error", err)
+	}
+}
+
+func TestResponseEnvelopeKey(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "statusCode": 200, "body": { "result": { "data": { "app": null }, "errors": [{ "message": "not found" }] } } }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+	WithResponseEnvelopeKey("result")(&client)
+
+	res, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || err.Error() != "not found" {
+		t.Fatal("Expected the error from inside the envelope", err)
+	}
+	if _, ok := (*res)["app"]; !ok {
+		t.Fatal("Expected the data from inside the envelope", *res)
+	}
+
+	mock.response.Payload = []byte(`{ "statusCode": 200, "body": { "data": { "result": true } } }`)
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if !errors.Is(err, ErrDecodeBody) {
+		t.Fatal("Expected a decode error without the envelope", err)
+	}
+}
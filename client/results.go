@@ -0,0 +1,81 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field returns data[key] as a T, with an error when the key is missing or
+// holds a value of another type. Note that JSON numbers decode as float64.
+func Field[T any](data map[string]interface{}, key string) (T, error) {
+	var zero T
+	value, ok := data[key]
+	if !ok {
+		return zero, fmt.Errorf("Field %q is missing", key)
+	}
+	typed, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("Field %q is a %T, not a %T", key, value, zero)
+	}
+	return typed, nil
+}
+
+// Get returns the value at a dotted path in data, such as
+// "app.versions.edges.0.node.id", where numeric segments index into lists.
+// It returns an error naming the first segment that is missing, out of range
+// or not an object or list.
+func Get(data map[string]interface{}, path string) (interface{}, error) {
+	var value interface{} = data
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		at := strings.Join(segments[:i+1], ".")
+		switch current := value.(type) {
+		case map[string]interface{}:
+			next, ok := current[segment]
+			if !ok {
+				return nil, fmt.Errorf("Path %q is missing", at)
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil {
+				return nil, fmt.Errorf("Path %q indexes a list with a non-numeric segment", at)
+			}
+			if index < 0 || index >= len(current) {
+				return nil, fmt.Errorf("Path %q is out of range for a list of %d", at, len(current))
+			}
+			value = current[index]
+		default:
+			return nil, fmt.Errorf("Path %q descends into a %T", at, value)
+		}
+	}
+	return value, nil
+}
+
+// ErrorPaths returns the paths of data affected by errs, in the dotted form
+// accepted by Get, mapped to the error at each path. Since GraphQL propagates
+// a failed non-null field up to its nearest nullable parent, each error path
+// is cut back to the longest prefix present in data, which is the value the
+// server nulled out. Errors without a path are skipped.
+func ErrorPaths(data map[string]interface{}, errs []GraphQLError) map[string]GraphQLError {
+	paths := map[string]GraphQLError{}
+	for _, gqlErr := range errs {
+		if len(gqlErr.Path) == 0 {
+			continue
+		}
+		segments := make([]string, len(gqlErr.Path))
+		for i, segment := range gqlErr.Path {
+			segments[i] = fmt.Sprint(segment)
+		}
+		end := len(segments)
+		for end > 1 {
+			if _, err := Get(data, strings.Join(segments[:end], ".")); err == nil {
+				break
+			}
+			end--
+		}
+		paths[strings.Join(segments[:end], ".")] = gqlErr
+	}
+	return paths
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestField(t *testing.T) {
+	data := map[string]interface{}{
+		"name":  "test",
+		"count": 2.0,
+		"app":   map[string]interface{}{"id": "some_id"},
+		"empty": nil,
+	}
+
+	name, err := Field[string](data, "name")
+	if err != nil || name != "test" {
+		t.Fatal("Did not get string field", name, err)
+	}
+	count, err := Field[float64](data, "count")
+	if err != nil || count != 2 {
+		t.Fatal("Did not get number field", count, err)
+	}
+	app, err := Field[map[string]interface{}](data, "app")
+	if err != nil || app["id"] != "some_id" {
+		t.Fatal("Did not get object field", app, err)
+	}
+
+	_, err = Field[string](data, "missing")
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatal("Expected missing field error", err)
+	}
+	_, err = Field[int](data, "count")
+	if err == nil || !strings.Contains(err.Error(), "float64") {
+		t.Fatal("Expected type mismatch error", err)
+	}
+	_, err = Field[string](data, "empty")
+	if err == nil {
+		t.Fatal("Expected error for null field")
+	}
+}
+
+func TestGet(t *testing.T) {
+	data := map[string]interface{}{
+		"app": map[string]interface{}{
+			"versions": map[string]interface{}{
+				"edges": []interface{}{
+					map[string]interface{}{"node": map[string]interface{}{"id": "some_id"}},
+				},
+			},
+			"name": "test",
+		},
+	}
+
+	id, err := Get(data, "app.versions.edges.0.node.id")
+	if err != nil || id != "some_id" {
+		t.Fatal("Did not get nested value", id, err)
+	}
+
+	cases := map[string]string{
+		"app.missing":                "\"app.missing\" is missing",
+		"app.versions.edges.1":       "out of range",
+		"app.versions.edges.first":   "non-numeric",
+		"app.name.first":             "descends into a string",
+		"app.versions.edges.0.other": "\"app.versions.edges.0.other\" is missing",
+	}
+	for path, message := range cases {
+		_, err := Get(data, path)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Fatal("Expected error for", path, err)
+		}
+	}
+}
+
+func TestErrorPaths(t *testing.T) {
+	data := map[string]interface{}{
+		"app": map[string]interface{}{
+			"versions": []interface{}{
+				map[string]interface{}{"id": "v1", "readme": nil},
+				nil,
+			},
+		},
+		"user": nil,
+	}
+	errs := []GraphQLError{
+		{Message: "readme failed", Path: []interface{}{"app", "versions", 0.0, "readme"}},
+		{Message: "version failed", Path: []interface{}{"app", "versions", 1.0, "id"}},
+		{Message: "user failed", Path: []interface{}{"user", "profile", "name"}},
+		{Message: "no path"},
+	}
+
+	paths := ErrorPaths(data, errs)
+	expected := map[string]string{
+		"app.versions.0.readme": "readme failed",
+		"app.versions.1":        "version failed",
+		"user":                  "user failed",
+	}
+	if len(paths) != len(expected) {
+		t.Fatal("Unexpected paths", paths)
+	}
+	for path, message := range expected {
+		if paths[path].Message != message {
+			t.Fatal("Did not map path", path, paths)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+type noRetryKey struct{}
+
+// ContextWithoutRetries returns a context whose requests are attempted only
+// once, whatever retryer the client was built with, for one-off calls such
+// as non-idempotent admin actions that must not be repeated when the Invoke
+// API answers with a throttling or server error.
+func ContextWithoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+func retriesDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noRetryKey{}).(bool)
+	return disabled
+}
+
+func withoutRetries(o *lambda.Options) {
+	o.Retryer = aws.NopRetryer{}
+}
+
+// idempotentMethods are the HTTP methods Do lets the AWS SDK retry.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// retryable reports whether a failed invocation of req may be retried: like
+// net/http, requests with idempotent methods or an Idempotency-Key header
+// are, and others only when the client was built WithNonIdempotentRetries.
+func (c *LambdaClient) retryable(req *http.Request) bool {
+	if c.retryNonIdempotent || idempotentMethods[req.Method] {
+		return true
+	}
+	_, hasKey := req.Header["Idempotency-Key"]
+	_, hasAltKey := req.Header["X-Idempotency-Key"]
+	return hasKey || hasAltKey
+}
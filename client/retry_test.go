@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// throttledClient builds a client whose every invocation is throttled, and
+// returns it with a counter of the attempts made.
+func throttledClient(t *testing.T, opts ...Option) (*LambdaClient, *int) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	attempts := 0
+	httpClient := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     http.Header{"X-Amzn-Errortype": []string{"TooManyRequestsException"}},
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{ "message": "Rate exceeded" }`)),
+			}, nil
+		}),
+	}
+	retryer := retry.NewStandard(func(o *retry.StandardOptions) {
+		o.MaxAttempts = 3
+		o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) {
+			return 0, nil
+		})
+	})
+	opts = append([]Option{WithHTTPClient(httpClient), WithAWSRetryer(retryer)}, opts...)
+	client, err := BuildClient("test-account", "test-user", map[string]bool{}, opts...)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	return client, &attempts
+}
+
+func TestContextWithoutRetries(t *testing.T) {
+	client, attempts := throttledClient(t)
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || *attempts != 3 {
+		t.Fatal("Expected throttling to be retried by default", *attempts, err)
+	}
+
+	*attempts = 0
+	_, err = client.GqlWithContext(ContextWithoutRetries(context.Background()), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err == nil || *attempts != 1 {
+		t.Fatal("Expected a single attempt without retries", *attempts, err)
+	}
+	if status, ok := InvokeStatusCode(err); !ok || status != http.StatusTooManyRequests {
+		t.Fatal("Expected the throttling status", status, ok, err)
+	}
+}
+
+func TestDoRetriesIdempotentMethods(t *testing.T) {
+	client, attempts := throttledClient(t)
+
+	req, _ := http.NewRequest("GET", "some-service:deployed/resource", nil)
+	_, err := client.Do(req)
+	if err == nil || *attempts != 3 {
+		t.Fatal("Expected a GET to be retried", *attempts, err)
+	}
+
+	*attempts = 0
+	req, _ = http.NewRequest("POST", "some-service:deployed/resource", bytes.NewBufferString("{}"))
+	_, err = client.Do(req)
+	if err == nil || *attempts != 1 {
+		t.Fatal("Expected a POST not to be retried", *attempts, err)
+	}
+
+	*attempts = 0
+	req, _ = http.NewRequest("POST", "some-service:deployed/resource", bytes.NewBufferString("{}"))
+	req.Header.Set("Idempotency-Key", "some-key")
+	_, err = client.Do(req)
+	if err == nil || *attempts != 3 {
+		t.Fatal("Expected a POST with an idempotency key to be retried", *attempts, err)
+	}
+
+	client, attempts = throttledClient(t, WithNonIdempotentRetries())
+	req, _ = http.NewRequest("PATCH", "some-service:deployed/resource", bytes.NewBufferString("{}"))
+	_, err = client.Do(req)
+	if err == nil || *attempts != 3 {
+		t.Fatal("Expected a PATCH to be retried when opted in", *attempts, err)
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// semaphore limits how many callers hold it at once. Callers that have to
+// wait are let in strictly in the order they arrived, so none can starve
+// under sustained load.
+type semaphore struct {
+	mutex   sync.Mutex
+	size    int
+	held    int
+	waiters list.List
+}
+
+func newSemaphore(size int) *semaphore {
+	return &semaphore{size: size}
+}
+
+// acquire takes a slot, waiting behind earlier callers if none is free. It
+// reports whether it had to wait, and gives up with the context error once
+// ctx is done, or with ErrClientClosed once closed is closed.
+func (s *semaphore) acquire(ctx context.Context, closed <-chan struct{}) (bool, error) {
+	s.mutex.Lock()
+	if s.held < s.size && s.waiters.Len() == 0 {
+		s.held++
+		s.mutex.Unlock()
+		return false, nil
+	}
+	ready := make(chan struct{})
+	waiter := s.waiters.PushBack(ready)
+	s.mutex.Unlock()
+
+	var err error
+	select {
+	case <-ready:
+		return true, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-closed:
+		err = ErrClientClosed
+	}
+	s.mutex.Lock()
+	select {
+	case <-ready:
+		// The slot was handed over just as the wait ended; pass it on.
+		s.mutex.Unlock()
+		s.release()
+	default:
+		s.waiters.Remove(waiter)
+		s.mutex.Unlock()
+	}
+	return true, err
+}
+
+// release frees a slot, handing it directly to the longest waiting caller.
+func (s *semaphore) release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if front := s.waiters.Front(); front != nil {
+		s.waiters.Remove(front)
+		close(front.Value.(chan struct{}))
+		return
+	}
+	s.held--
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+// waitForWaiters reports whether n callers are waiting on s within a second.
+func waitForWaiters(s *semaphore, n int) bool {
+	for i := 0; i < 1000; i++ {
+		s.mutex.Lock()
+		waiting := s.waiters.Len()
+		s.mutex.Unlock()
+		if waiting == n {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+func TestSemaphoreIsFIFO(t *testing.T) {
+	s := newSemaphore(1)
+	waited, err := s.acquire(context.Background(), nil)
+	if waited || err != nil {
+		t.Fatal("Should acquire a free slot without waiting", waited, err)
+	}
+
+	var mutex sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			waited, err := s.acquire(context.Background(), nil)
+			if !waited || err != nil {
+				t.Error("Expected to wait for a slot", waited, err)
+			}
+			mutex.Lock()
+			order = append(order, i)
+			mutex.Unlock()
+			s.release()
+		}(i)
+		if !waitForWaiters(s, i+1) {
+			t.Fatal("Timed out waiting for waiter", i)
+		}
+	}
+	s.release()
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatal("Waiters were not served in order", order)
+		}
+	}
+}
+
+func TestSemaphoreCancelledWaiter(t *testing.T) {
+	s := newSemaphore(1)
+	s.acquire(context.Background(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := s.acquire(ctx, nil)
+		done <- err
+	}()
+	if !waitForWaiters(s, 1) {
+		t.Fatal("Timed out waiting for the waiter")
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatal("Expected the context error", err)
+	}
+	if !waitForWaiters(s, 0) {
+		t.Fatal("The cancelled waiter should leave the queue")
+	}
+
+	s.release()
+	waited, err := s.acquire(context.Background(), nil)
+	if waited || err != nil {
+		t.Fatal("A cancelled waiter should not hold a slot", waited, err)
+	}
+}
+
+func TestQueueWait(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": "{ \"data\": { \"result\": true } }" }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+	WithMaxConcurrency(1)(&client)
+
+	resp, err := client.GqlWithMetadata(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil || resp.QueueWait != 0 {
+		t.Fatal("Should not wait for a free slot", resp, err)
+	}
+
+	client.invokeSlots.acquire(context.Background(), nil)
+	go func() {
+		waitForWaiters(client.invokeSlots, 1)
+		time.Sleep(10 * time.Millisecond)
+		client.invokeSlots.release()
+	}()
+	resp, err = client.GqlWithMetadata(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil || resp.QueueWait < 10*time.Millisecond {
+		t.Fatal("Expected the time spent queued", resp, err)
+	}
+}
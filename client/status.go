@@ -0,0 +1,52 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// Every request made by the client has two status codes. The invoke status
+// code is the HTTP status of the call to the Lambda Invoke API itself: 200
+// for a RequestResponse invocation that reached the function, or an error
+// status when Lambda could not run it. The application status code is the
+// statusCode the function put in its API Gateway style response, which Do
+// returns as the status of the http.Response.
+//
+// Invoke-level failures are returned as errors, and InvokeStatusCode
+// recovers their status code.
+
+// invokeStatusError is returned when the Invoke API answers with a status code
+// that does not match the invocation type used.
+type invokeStatusError struct {
+	invocationType types.InvocationType
+	expected       int32
+	statusCode     int32
+}
+
+func (e *invokeStatusError) Error() string {
+	return fmt.Sprintf("%v: expected %d for %s invocation, got %d", ErrUnexpectedInvokeStatus, e.expected, e.invocationType, e.statusCode)
+}
+
+func (e *invokeStatusError) Unwrap() error {
+	return ErrUnexpectedInvokeStatus
+}
+
+// InvokeStatusCode returns the HTTP status code of the Lambda Invoke API call
+// that failed with err, such as a 429 when the function is throttled or a
+// 5xx when the Lambda service fails. It returns false for errors that did not
+// come from the Invoke API, including failures reported by the function
+// itself.
+func InvokeStatusCode(err error) (int, bool) {
+	var statusErr *invokeStatusError
+	if errors.As(err, &statusErr) {
+		return int(statusErr.statusCode), true
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode(), true
+	}
+	return 0, false
+}
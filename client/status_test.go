@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func TestGqlResponseStatusCodes(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			StatusCode: 200,
+			Payload:    []byte(`{ "statusCode": 207, "body": { "data": { "result": true } } }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	resp, err := client.GqlWithMetadata(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if resp.InvokeStatusCode != 200 || resp.StatusCode != 207 {
+		t.Fatal("Expected both status codes", resp.InvokeStatusCode, resp.StatusCode)
+	}
+}
+
+func TestInvokeStatusCode(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{StatusCode: 200},
+	}
+	client := LambdaClient{
+		invoker:        &mock,
+		invocationType: types.InvocationTypeEvent,
+	}
+
+	_, err := client.invoke(context.Background(), "some_lambda", []byte("{}"))
+	if status, ok := InvokeStatusCode(err); !ok || status != 200 || !errors.Is(err, ErrUnexpectedInvokeStatus) {
+		t.Fatal("Expected the unexpected invoke status", status, ok, err)
+	}
+
+	mock.err = &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusTooManyRequests}},
+			Err:      errors.New("throttled"),
+		},
+	}
+	_, err = client.invoke(context.Background(), "some_lambda", []byte("{}"))
+	if status, ok := InvokeStatusCode(err); !ok || status != http.StatusTooManyRequests {
+		t.Fatal("Expected the status of the failed invoke call", status, ok, err)
+	}
+
+	if _, ok := InvokeStatusCode(GraphQLError{Message: "failed"}); ok {
+		t.Fatal("Application errors have no invoke status code")
+	}
+}
@@ -1,12 +1,16 @@
 package client
 
+import "context"
+
 type MockClient struct {
 	hasBeenCalled bool
+	ctx           context.Context
 	response      *map[string]interface{}
 	error         error
 }
 
-func (m *MockClient) Gql(url string, operation string, variables map[string]interface{}) (*map[string]interface{}, error) {
+func (m *MockClient) GqlWithContext(ctx context.Context, url string, operation string, variables map[string]interface{}) (*map[string]interface{}, error) {
 	m.hasBeenCalled = true
+	m.ctx = ctx
 	return m.response, m.error
 }
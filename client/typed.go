@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Query sends query like GqlWithContext and decodes the data of the response
+// into a T with mapstructure, the same way the sub-clients decode their
+// results. Fields match response keys case-insensitively by name, or by
+// their mapstructure tag.
+//
+// The keys of a response are the aliases of the fields a query selects
+// rather than the field names, so a query such as
+//
+//	query { myApp: app(id: "1") { id } otherApp: app(id: "2") { id } }
+//
+// decodes into fields tagged with the aliases:
+//
+//	type result struct {
+//		MyApp    App `mapstructure:"myApp"`
+//		OtherApp App `mapstructure:"otherApp"`
+//	}
+//
+// When the response holds partial data along with errors, the decoded
+// partial data is returned with the error. A hook set WithResultHook is
+// called with the decoded *T before it is returned.
+func Query[T any](ctx context.Context, c *LambdaClient, uri string, query string, variables map[string]interface{}) (*T, error) {
+	res, err := c.GqlWithContext(ctx, uri, query, variables)
+	if res == nil {
+		return nil, err
+	}
+	var result T
+	decodeErr := mapstructure.Decode(*res, &result)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	hookErr := c.runResultHook(&result)
+	if hookErr != nil {
+		return nil, hookErr
+	}
+	return &result, err
+}
+
+// runResultHook calls the hook set WithResultHook, if any, with a pointer to
+// a decoded result.
+func (c *LambdaClient) runResultHook(v interface{}) error {
+	if c.resultHook == nil {
+		return nil
+	}
+	err := c.resultHook(v)
+	if err != nil {
+		return fmt.Errorf("Result hook failed: %w", err)
+	}
+	return nil
+}
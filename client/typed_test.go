@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestQueryDecodesAliases(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": { "data": { "myApp": { "id": "1", "name": "first" }, "otherApp": { "id": "2", "name": "second" }, "count": 2 } } }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+	type app struct {
+		Id   string
+		Name string
+	}
+	type result struct {
+		MyApp    app `mapstructure:"myApp"`
+		OtherApp app `mapstructure:"otherApp"`
+		Count    int
+	}
+
+	res, err := Query[result](context.Background(), &client, "some_lambda:status/some/path", `query { myApp: app(id: "1") { id name } otherApp: app(id: "2") { id name } count }`, nil)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if res.MyApp.Id != "1" || res.OtherApp.Name != "second" || res.Count != 2 {
+		t.Fatal("Did not decode aliased fields", *res)
+	}
+
+	mock.response.Payload = []byte(`{ "body": { "data": { "myApp": { "id": "1" }, "otherApp": null }, "errors": [{ "message": "not found", "path": ["otherApp"] }] } }`)
+	res, err = Query[result](context.Background(), &client, "some_lambda:status/some/path", MOCK_MUTATION, nil)
+	if err == nil || err.Error() != "not found" {
+		t.Fatal("Expected the GraphQL error", err)
+	}
+	if res == nil || res.MyApp.Id != "1" {
+		t.Fatal("Expected partial data along with the error", res)
+	}
+
+	mock.response.Payload = []byte(`{ "body": { "data": { "myApp": "not an object" } } }`)
+	_, err = Query[result](context.Background(), &client, "some_lambda:status/some/path", MOCK_MUTATION, nil)
+	if err == nil {
+		t.Fatal("Expected a decode error")
+	}
+}
+
+func TestQueryResultHook(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": { "data": { "app": { "id": "1", "icon": "icons/1.png" } } } }`),
+		},
+	}
+	type result struct {
+		App struct {
+			Id   string
+			Icon string
+		}
+	}
+	hookErr := errors.New("hook failed")
+	var fail bool
+	client := (&LambdaClient{invoker: &mock}).Clone(WithResultHook(func(v interface{}) error {
+		if fail {
+			return hookErr
+		}
+		if res, ok := v.(*result); ok {
+			res.App.Icon = "https://apps.example.com/" + res.App.Icon
+		}
+		return nil
+	}))
+
+	res, err := Query[result](context.Background(), client, "some_lambda:status/some/path", MOCK_MUTATION, nil)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if res.App.Icon != "https://apps.example.com/icons/1.png" {
+		t.Fatal("Expected the hook to modify the result", *res)
+	}
+
+	fail = true
+	res, err = Query[result](context.Background(), client, "some_lambda:status/some/path", MOCK_MUTATION, nil)
+	if res != nil || !errors.Is(err, hookErr) {
+		t.Fatal("Expected the hook error", res, err)
+	}
+
+	type app struct {
+		Id string
+	}
+	paged := (&LambdaClient{invoker: &pagingInvoker{total: 1}}).Clone(WithResultHook(func(v interface{}) error {
+		if fail {
+			return hookErr
+		}
+		if node, ok := v.(*app); ok {
+			node.Id = "hooked-" + node.Id
+		}
+		return nil
+	}))
+	_, _, err = NewCursor[app](context.Background(), paged, "some_lambda:status/some/path", MOCK_LIST_QUERY, nil, 1).Next()
+	if !errors.Is(err, hookErr) {
+		t.Fatal("Expected the hook error from the cursor", err)
+	}
+	fail = false
+	node, ok, err := NewCursor[app](context.Background(), paged, "some_lambda:status/some/path", MOCK_LIST_QUERY, nil, 1).Next()
+	if err != nil || !ok || node.Id != "hooked-app-0" {
+		t.Fatal("Expected the hook to modify cursor nodes", node, ok, err)
+	}
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrVariableConflict is returned by MergeVariables when two maps give the
+// same variable different values.
+var ErrVariableConflict = errors.New("Conflicting variable values")
+
+// MergeVariables combines several variable maps into a new one, such as the
+// variables required by each fragment of a query. A variable may appear in
+// more than one map as long as its values are deeply equal; otherwise
+// ErrVariableConflict is returned.
+func MergeVariables(maps ...map[string]interface{}) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	for _, variables := range maps {
+		for k, v := range variables {
+			if existing, ok := merged[k]; ok && !reflect.DeepEqual(existing, v) {
+				return nil, fmt.Errorf("%w: %s is both %v and %v", ErrVariableConflict, k, existing, v)
+			}
+			merged[k] = v
+		}
+	}
+	return merged, nil
+}
+
+// omitNullVariables returns a copy of variables without the variables whose
+// value is nil or a nil pointer, so they are sent as absent rather than as
+// an explicit null. Nulls nested inside input objects are kept.
+func omitNullVariables(variables map[string]interface{}) map[string]interface{} {
+	omitted := make(map[string]interface{}, len(variables))
+	for k, v := range variables {
+		if v == nil {
+			continue
+		}
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			continue
+		}
+		omitted[k] = v
+	}
+	return omitted
+}
+
+// coerceVariables returns a copy of variables with values that commonly
+// trip up GraphQL servers normalized: times become RFC 3339 strings in UTC
+// with any fractional seconds kept, and json.Numbers become an int64 or
+// float64. Integers too large for an int64 stay json.Numbers so they are sent
+// exactly as given. Slices and string-keyed maps of any type are copied and
+// coerced recursively. Values that are unlikely to encode the way the schema
+// expects are logged with logf.
+func coerceVariables(variables map[string]interface{}, logf func(string, ...interface{})) map[string]interface{} {
+	coerced := make(map[string]interface{}, len(variables))
+	for k, v := range variables {
+		coerced[k] = coerceValue(k, v, logf)
+	}
+	return coerced
+}
+
+func coerceValue(path string, value interface{}, logf func(string, ...interface{})) interface{} {
+	switch v := value.(type) {
+	case nil, string, bool, int, int32, int64, float32, float64:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return v.UTC().Format(time.RFC3339Nano)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, err := v.Float64()
+		if err != nil {
+			logf("Variable %s is not a valid number: %q", path, v)
+			return v
+		}
+		if strings.ContainsAny(string(v), ".eE") {
+			return f
+		}
+		// An integer beyond the range of int64 would lose precision as a
+		// float64, and a json.Number is encoded as its literal.
+		return v
+	case map[string]interface{}:
+		if v == nil {
+			return nil
+		}
+		coerced := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			coerced[k] = coerceValue(path+"."+k, item, logf)
+		}
+		return coerced
+	case []interface{}:
+		if v == nil {
+			return nil
+		}
+		coerced := make([]interface{}, len(v))
+		for i, item := range v {
+			coerced[i] = coerceValue(path+"."+strconv.Itoa(i), item, logf)
+		}
+		return coerced
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if rv.IsNil() {
+			return nil
+		}
+		coerced := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			coerced[k] = coerceValue(path+"."+k, iter.Value().Interface(), logf)
+		}
+		return coerced
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// Byte slices are encoded as base64 strings.
+			break
+		}
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		coerced := make([]interface{}, rv.Len())
+		for i := range coerced {
+			coerced[i] = coerceValue(path+"."+strconv.Itoa(i), rv.Index(i).Interface(), logf)
+		}
+		return coerced
+	}
+	switch reflect.Indirect(rv).Kind() {
+	case reflect.Struct:
+		logf("Variable %s is a %T, which is encoded with its Go field names unless it has json tags", path, value)
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		logf("Variable %s is a %T, which can not be encoded as JSON", path, value)
+	}
+	return value
+}
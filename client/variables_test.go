@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestCoerceVariables(t *testing.T) {
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+
+	type input struct {
+		Name string
+	}
+	created := time.Date(2022, 1, 2, 3, 4, 5, 600, time.FixedZone("EST", -5*3600))
+	variables := map[string]interface{}{
+		"created":  created,
+		"count":    json.Number("3"),
+		"ratio":    json.Number("0.5"),
+		"list":     []interface{}{&created},
+		"input":    input{Name: "test"},
+		"precise":  created.Add(123456 * time.Microsecond),
+		"big":      json.Number("12345678901234567890"),
+		"items":    []map[string]interface{}{{"at": created}},
+		"labels":   map[string][]time.Time{"at": {created}},
+		"bytes":    []byte("raw"),
+		"nilMap":   map[string]interface{}(nil),
+		"nilList":  []interface{}(nil),
+		"nilTyped": map[string]string(nil),
+	}
+
+	coerced := coerceVariables(variables, logger.Printf)
+	if coerced["created"] != "2022-01-02T08:04:05.0000006Z" {
+		t.Fatal("Did not coerce time", coerced["created"])
+	}
+	if coerced["count"] != int64(3) || coerced["ratio"] != 0.5 {
+		t.Fatal("Did not coerce numbers", coerced["count"], coerced["ratio"])
+	}
+	if coerced["list"].([]interface{})[0] != "2022-01-02T08:04:05.0000006Z" {
+		t.Fatal("Did not coerce nested time", coerced["list"])
+	}
+	if coerced["precise"] != "2022-01-02T08:04:05.1234566Z" {
+		t.Fatal("Did not keep sub-second precision", coerced["precise"])
+	}
+	if big, _ := json.Marshal(coerced["big"]); string(big) != "12345678901234567890" {
+		t.Fatal("Large integers should be sent exactly", string(big))
+	}
+	if coerced["items"].([]interface{})[0].(map[string]interface{})["at"] != "2022-01-02T08:04:05.0000006Z" {
+		t.Fatal("Did not coerce a typed slice", coerced["items"])
+	}
+	if coerced["labels"].(map[string]interface{})["at"].([]interface{})[0] != "2022-01-02T08:04:05.0000006Z" {
+		t.Fatal("Did not coerce a typed map", coerced["labels"])
+	}
+	if _, ok := coerced["bytes"].([]byte); !ok {
+		t.Fatal("Byte slices should be left alone", coerced["bytes"])
+	}
+	for _, name := range []string{"nilMap", "nilList", "nilTyped"} {
+		if coerced[name] != nil {
+			t.Fatal("Nil collections should stay null", name, coerced[name])
+		}
+	}
+	if variables["created"] != created {
+		t.Fatal("Should not modify the given variables", variables)
+	}
+	if !strings.Contains(logs.String(), "Variable input is a client.input") {
+		t.Fatal("Expected a warning for the struct variable", logs.String())
+	}
+}
+
+func TestMergeVariables(t *testing.T) {
+	merged, err := MergeVariables(
+		map[string]interface{}{"project": "some_project", "filter": map[string]interface{}{"name": "test"}},
+		map[string]interface{}{"project": "some_project", "first": 10},
+		nil,
+		map[string]interface{}{"filter": map[string]interface{}{"name": "test"}},
+	)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if len(merged) != 3 || merged["project"] != "some_project" || merged["first"] != 10 {
+		t.Fatal("Did not merge variables", merged)
+	}
+
+	_, err = MergeVariables(
+		map[string]interface{}{"project": "some_project"},
+		map[string]interface{}{"project": "other_project"},
+	)
+	if !errors.Is(err, ErrVariableConflict) || !strings.Contains(err.Error(), "project") {
+		t.Fatal("Expected a conflict error", err)
+	}
+}
+
+func TestOmitNullVariables(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "statusCode": 200, "body": "{ \"data\": { \"result\": true } }" }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+	var missing *string
+	variables := map[string]interface{}{
+		"id":     "1",
+		"cursor": nil,
+		"filter": missing,
+		"input":  map[string]interface{}{"name": nil},
+	}
+	sentVariables := func() map[string]interface{} {
+		var body struct {
+			Variables map[string]interface{}
+		}
+		sent, _ := DecodePayload(mock.payload.Payload)
+		json.Unmarshal([]byte(sent.Body), &body)
+		return body.Variables
+	}
+
+	_, err := client.Gql("some_lambda:status/some/path", MOCK_MUTATION, variables)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent := sentVariables()
+	if _, ok := sent["cursor"]; !ok {
+		t.Fatal("Explicit nulls should be sent by default", sent)
+	}
+
+	WithOmitNullVariables()(&client)
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, variables)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	sent = sentVariables()
+	if _, ok := sent["cursor"]; ok {
+		t.Fatal("Did not omit the nil variable", sent)
+	}
+	if _, ok := sent["filter"]; ok {
+		t.Fatal("Did not omit the nil pointer variable", sent)
+	}
+	if _, ok := sent["input"].(map[string]interface{})["name"]; !ok || sent["id"] != "1" {
+		t.Fatal("Should keep other variables and nested nulls", sent)
+	}
+	if _, ok := variables["cursor"]; !ok {
+		t.Fatal("Should not modify the given variables", variables)
+	}
+}
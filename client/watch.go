@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// WatchOption configures Watch.
+type WatchOption func(*watchSettings)
+
+type watchSettings struct {
+	onlyChanges bool
+}
+
+// WatchOnlyChanges makes Watch skip results that are the same as the last one
+// it sent, so consumers only hear about changes.
+func WatchOnlyChanges() WatchOption {
+	return func(s *watchSettings) {
+		s.onlyChanges = true
+	}
+}
+
+// Watch approximates a live query over RequestResponse invocations, which can
+// not stream, by sending query right away and then again interval after each
+// result, and sending each result on the returned channel. Results holding
+// partial data carry both the data and the GraphQL error. A poll is not
+// started until the previous result has been received, so a slow consumer
+// slows polling down rather than building up a backlog. Polling stops, and
+// the channel is closed, when ctx is done, the returned stop function is
+// called or the client is closed. Watch returns an error for an interval
+// that is not positive.
+func (c *LambdaClient) Watch(ctx context.Context, uri string, query string, variables map[string]interface{}, interval time.Duration, opts ...WatchOption) (<-chan GqlResult, func(), error) {
+	if interval <= 0 {
+		return nil, nil, fmt.Errorf("Watch interval must be positive, got %s", interval)
+	}
+	var settings watchSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	ctx, stop := context.WithCancel(ctx)
+	if c.done != nil {
+		go func() {
+			select {
+			case <-c.done:
+				stop()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	results := make(chan GqlResult)
+	go func() {
+		defer close(results)
+		var last *GqlResult
+		for {
+			var result GqlResult
+			data, err := c.GqlWithContext(ctx, uri, query, variables)
+			if ctx.Err() != nil {
+				return
+			}
+			if data != nil {
+				result.Data = *data
+			}
+			result.Err = err
+			if !settings.onlyChanges || last == nil || !sameResult(*last, result) {
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+				last = &result
+			}
+			if errors.Is(err, ErrClientClosed) || !c.wait(ctx, interval) {
+				return
+			}
+		}
+	}()
+	return results, stop, nil
+}
+
+// sameResult reports whether two polls returned the same data and error.
+func sameResult(a GqlResult, b GqlResult) bool {
+	if (a.Err == nil) != (b.Err == nil) || (a.Err != nil && a.Err.Error() != b.Err.Error()) {
+		return false
+	}
+	return reflect.DeepEqual(a.Data, b.Data)
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+// pollInvoker answers the nth request with a count of n/2, so the result
+// changes every second poll.
+type pollInvoker struct {
+	requests int32
+}
+
+func (p *pollInvoker) Invoke(ctx context.Context, input *lambda.InvokeInput, rest ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	n := atomic.AddInt32(&p.requests, 1) - 1
+	payload := fmt.Sprintf(`{ "statusCode": 200, "body": { "data": { "count": %d } } }`, n/2)
+	return &lambda.InvokeOutput{Payload: []byte(payload)}, nil
+}
+
+func TestWatch(t *testing.T) {
+	invoker := pollInvoker{}
+	var waited []time.Duration
+	client := LambdaClient{
+		invoker: &invoker,
+	}
+	withTimer(func(d time.Duration) <-chan time.Time {
+		waited = append(waited, d)
+		fired := make(chan time.Time, 1)
+		fired <- time.Time{}
+		return fired
+	})(&client)
+
+	results, stop, err := client.Watch(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, nil, time.Minute)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	for i := 0; i < 4; i++ {
+		result := <-results
+		if result.Err != nil || result.Data["count"] != float64(i/2) {
+			t.Fatal("Expected every poll to be sent", i, result)
+		}
+	}
+	stop()
+	for range results {
+	}
+	if len(waited) < 3 || waited[0] != time.Minute {
+		t.Fatal("Expected to wait the interval between polls", waited)
+	}
+
+	atomic.StoreInt32(&invoker.requests, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	results, _, _ = client.Watch(ctx, "some_lambda:status/some/path", MOCK_MUTATION, nil, time.Millisecond, WatchOnlyChanges())
+	for i := 0; i < 3; i++ {
+		result := <-results
+		if result.Err != nil || result.Data["count"] != float64(i) {
+			t.Fatal("Expected only changed results", i, result)
+		}
+	}
+	cancel()
+	for range results {
+	}
+	if polls := atomic.LoadInt32(&invoker.requests); polls < 5 {
+		t.Fatal("Expected unchanged polls to be skipped", polls)
+	}
+}
+
+func TestWatchInvalidInterval(t *testing.T) {
+	client := LambdaClient{
+		invoker: &pollInvoker{},
+	}
+	results, stop, err := client.Watch(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, nil, 0)
+	if err == nil || results != nil || stop != nil {
+		t.Fatal("Expected an error for a zero interval", err)
+	}
+}
+
+func TestWatchPartialData(t *testing.T) {
+	mock := MockInvoker{
+		response: &lambda.InvokeOutput{
+			Payload: []byte(`{ "body": { "data": { "app": null, "count": 1 }, "errors": [{ "message": "not found", "path": ["app"] }] } }`),
+		},
+	}
+	client := LambdaClient{
+		invoker: &mock,
+	}
+
+	results, stop, _ := client.Watch(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, nil, time.Minute)
+	defer stop()
+	result := <-results
+	if result.Err == nil || result.Data["count"] != float64(1) {
+		t.Fatal("Expected the partial data along with the error", result)
+	}
+}
+
+func TestWatchStopsWhenClosed(t *testing.T) {
+	client := &LambdaClient{
+		invoker: &pollInvoker{},
+	}
+	client.Close()
+
+	results, _, _ := client.Watch(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, nil, time.Millisecond)
+	result := <-results
+	if !errors.Is(result.Err, ErrClientClosed) {
+		t.Fatal("Expected the closed client error", result)
+	}
+	select {
+	case _, ok := <-results:
+		if ok {
+			t.Fatal("Expected polling to stop")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the channel to be closed")
+	}
+
+	client = (&LambdaClient{invoker: &pollInvoker{}}).Clone()
+	results, _, _ = client.Watch(context.Background(), "some_lambda:status/some/path", MOCK_MUTATION, nil, time.Hour)
+	<-results
+	client.Close()
+	select {
+	case _, ok := <-results:
+		if ok {
+			t.Fatal("Expected polling to stop")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close should stop a watch waiting for its next poll")
+	}
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+const traceHeader = "X-Amzn-Trace-Id"
+
+type traceIDKey struct{}
+
+// ContextWithTraceID returns a context whose requests are part of the given
+// X-Ray trace. traceID is an X-Amzn-Trace-Id header value such as
+// "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1".
+// It is only used by clients built with WithXRayTracing.
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+func traceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// newTraceID generates a new X-Ray root trace id.
+func (c *LambdaClient) newTraceID() (string, error) {
+	id, err := c.randomHex(12)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Root=1-%08x-%s", c.now().Unix(), id), nil
+}
+
+// traceContext makes sure ctx carries a trace id when X-Ray tracing is
+// enabled. The id comes from ctx, then from the Lambda environment when
+// running inside a function, and is generated otherwise.
+func (c *LambdaClient) traceContext(ctx context.Context) (context.Context, error) {
+	if !c.xrayTracing || traceIDFromContext(ctx) != "" {
+		return ctx, nil
+	}
+	traceID := os.Getenv("_X_AMZN_TRACE_ID")
+	if traceID == "" {
+		var err error
+		traceID, err = c.newTraceID()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ContextWithTraceID(ctx, traceID), nil
+}
+
+// withTraceHeader sets the trace header on the Invoke API request itself,
+// which is what Lambda uses to populate _X_AMZN_TRACE_ID for the function.
+func withTraceHeader(traceID string) func(*lambda.Options) {
+	return func(o *lambda.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Build.Add(middleware.BuildMiddlewareFunc("XRayTraceHeader", func(
+				ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler,
+			) (middleware.BuildOutput, middleware.Metadata, error) {
+				if req, ok := in.Request.(*smithyhttp.Request); ok {
+					req.Header.Set(traceHeader, traceID)
+				}
+				return next.HandleBuild(ctx, in)
+			}), middleware.After)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestXRayTracing(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("_X_AMZN_TRACE_ID", "")
+
+	var requests []*http.Request
+	var payloads []Payload
+	httpClient := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var sent Payload
+			body, _ := ioutil.ReadAll(req.Body)
+			json.Unmarshal(body, &sent)
+			requests = append(requests, req)
+			payloads = append(payloads, sent)
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(bytes.NewBufferString("{ \"body\": \"{ \\\"data\\\": { \\\"result\\\": true }}\"}")),
+			}, nil
+		}),
+	}
+
+	client, err := BuildClient("test-account", "test-user", map[string]bool{}, WithHTTPClient(httpClient), WithXRayTracing())
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	traceID := "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1"
+	ctx := ContextWithTraceID(context.Background(), traceID)
+	_, err = client.GqlWithContext(ctx, "some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if requests[0].Header.Get("X-Amzn-Trace-Id") != traceID {
+		t.Fatal("Did not set trace header on the invoke request", requests[0].Header)
+	}
+	if payloads[0].Headers["X-Amzn-Trace-Id"] != traceID {
+		t.Fatal("Did not forward trace header in payload", payloads[0].Headers)
+	}
+
+	_, err = client.Gql("some_lambda:status/some/path", MOCK_MUTATION, map[string]interface{}{})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	generated := requests[1].Header.Get("X-Amzn-Trace-Id")
+	if !strings.HasPrefix(generated, "Root=1-") {
+		t.Fatal("Expected a generated trace id", generated)
+	}
+	if payloads[1].Headers["X-Amzn-Trace-Id"] != generated {
+		t.Fatal("Payload and invoke trace ids should match", payloads[1].Headers)
+	}
+}
+
+func TestXRayTracingDisabled(t *testing.T) {
+	client := LambdaClient{}
+	ctx, err := client.traceContext(ContextWithTraceID(context.Background(), "Root=1-abc"))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	headers, err := client.buildHeaders(ctx)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if _, ok := headers["X-Amzn-Trace-Id"]; ok {
+		t.Fatal("Should not send trace header unless enabled", headers)
+	}
+}